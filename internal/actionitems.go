@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	actionItemDoneActionID = "action_item_done"
+	actionItemBlockPrefix  = "action_item_"
+	// Slack allows 50 blocks per message; one is the header and one is kept
+	// for the overflow note.
+	maxRenderedActionItems = 48
+)
+
+func (s *IncidentService) CreateActionItems(ctx context.Context, channelID string) error {
+	return s.withIncident(ctx, channelID, func(incident *Incident) error {
+		return s.renderActionItems(ctx, incident)
+	})
+}
+
+// AddActionItem records an action item, assigned to assignee if that isn't
+// empty, and re-renders the pinned action item message. Adding a description
+// that is already listed is a no-op.
+func (s *IncidentService) AddActionItem(ctx context.Context, channelID, userID, assignee, description string) error {
+	return s.withIncident(ctx, channelID, func(incident *Incident) error {
+		return s.addActionItem(ctx, incident, userID, assignee, description)
+	})
+}
+
+// addActionItem is AddActionItem for callers that already hold the incident
+// lock.
+func (s *IncidentService) addActionItem(ctx context.Context, incident *Incident, userID, assignee, description string) error {
+	if incident.ActionItemsTS == "" {
+		err := s.importLegacyActionItems(ctx, incident)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to import legacy action items message", "incidentID", incident.ID, "error", err)
+		}
+	}
+
+	items, err := s.store.ListActionItems(ctx, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list action items: %w", err)
+	}
+	for _, item := range items {
+		if item.Description == description {
+			return nil
+		}
+	}
+
+	item := &ActionItem{
+		IncidentID:  incident.ID,
+		Description: description,
+		User:        userID,
+		Assignee:    assignee,
+	}
+	if err := s.store.AddActionItem(ctx, item); err != nil {
+		return fmt.Errorf("failed to store action item: %w", err)
+	}
+	s.webhooks.Publish(ctx, WebhookPayload{Event: WebhookActionItemAdded, Incident: incident, ActionItem: item})
+
+	return s.renderActionItems(ctx, incident)
+}
+
+// SetActionItemCompleted marks action item number n of the channel's incident
+// as done (or not done) and re-renders the pinned message.
+func (s *IncidentService) SetActionItemCompleted(ctx context.Context, channelID, userID string, number int, completed bool) (*ActionItem, error) {
+	var item *ActionItem
+	err := s.withIncident(ctx, channelID, func(incident *Incident) error {
+		var err error
+		item, err = s.store.GetActionItem(ctx, incident.ID, number)
+		if err != nil {
+			return err
+		}
+
+		if item.Completed != completed {
+			item.Completed = completed
+			item.CompletedBy = ""
+			item.CompletedAt = nil
+			if completed {
+				now := time.Now().UTC()
+				item.CompletedBy = userID
+				item.CompletedAt = &now
+			}
+			err = s.store.UpdateActionItem(ctx, item)
+			if err != nil {
+				return fmt.Errorf("failed to store action item: %w", err)
+			}
+		}
+
+		return s.renderActionItems(ctx, incident)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *IncidentService) ListActionItems(ctx context.Context, channelID string) ([]ActionItem, error) {
+	incident, err := s.incidentForChannel(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load incident: %w", err)
+	}
+	return s.store.ListActionItems(ctx, incident.ID)
+}
+
+// renderActionItems rewrites the pinned action item message from the stored
+// items. Callers must hold the incident lock.
+func (s *IncidentService) renderActionItems(ctx context.Context, incident *Incident) error {
+	items, err := s.store.ListActionItems(ctx, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list action items: %w", err)
+	}
+	return s.syncPinnedMessage(ctx, incident, &incident.ActionItemsTS, actionItemBlocks(items))
+}
+
+// actionItemBlocks renders one section per item with a checkbox to mark it
+// done. The checkbox's block ID carries the item number.
+func actionItemBlocks(items []ActionItem) []slack.Block {
+	headerText := slack.NewTextBlockObject("mrkdwn", "*Action Items*", false, false)
+	blocks := []slack.Block{slack.NewSectionBlock(headerText, nil, nil)}
+
+	for i, item := range items {
+		if i == maxRenderedActionItems {
+			more := slack.NewTextBlockObject("mrkdwn",
+				fmt.Sprintf("…and %d more. Use `/incident ai list` to see them all.", len(items)-i), false, false)
+			blocks = append(blocks, slack.NewContextBlock("", more))
+			break
+		}
+
+		doneOption := slack.NewOptionBlockObject(strconv.Itoa(item.Number),
+			slack.NewTextBlockObject("plain_text", "Done", false, false), nil)
+		checkbox := slack.NewCheckboxGroupsBlockElement(actionItemDoneActionID, doneOption)
+		if item.Completed {
+			checkbox.InitialOptions = []*slack.OptionBlockObject{doneOption}
+		}
+
+		text := slack.NewTextBlockObject("mrkdwn", actionItemLine(item), false, false)
+		blocks = append(blocks, slack.NewSectionBlock(text, nil, slack.NewAccessory(checkbox),
+			slack.SectionBlockOptionBlockID(actionItemBlockPrefix+strconv.Itoa(item.Number))))
+	}
+
+	return blocks
+}
+
+func actionItemLine(item ActionItem) string {
+	description := item.Description
+	if item.Completed {
+		description = "~" + description + "~"
+	}
+
+	line := fmt.Sprintf("%d. %s", item.Number, description)
+	if item.User != "" {
+		line = fmt.Sprintf("%d. <@%s> - %s", item.Number, item.User, description)
+	}
+	if item.Assignee != "" {
+		line += fmt.Sprintf(" (assigned to <@%s>)", item.Assignee)
+	}
+	if item.Completed && item.CompletedBy != "" {
+		line += fmt.Sprintf(" (done by <@%s>)", item.CompletedBy)
+	}
+	return line
+}
+
+// ActionItemsListMessage renders the `/incident ai list` response.
+func (s *IncidentService) ActionItemsListMessage(items []ActionItem) []slack.Block {
+	if len(items) == 0 {
+		return []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "This incident has no action items yet.", false, false), nil, nil),
+		}
+	}
+
+	open := 0
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		mark := ":white_check_mark:"
+		if !item.Completed {
+			mark = ":white_large_square:"
+			open++
+		}
+		lines = append(lines, mark+" "+actionItemLine(item))
+	}
+
+	header := fmt.Sprintf("*Action Items* (%d open, %d done)", open, len(items)-open)
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", header, false, false), nil, nil)}
+	for len(lines) > 0 {
+		// Keep each section comfortably under Slack's 3000 character limit.
+		n := min(len(lines), 20)
+		text := slack.NewTextBlockObject("mrkdwn", strings.Join(lines[:n], "\n"), false, false)
+		blocks = append(blocks, slack.NewSectionBlock(text, nil, nil))
+		lines = lines[n:]
+	}
+	return blocks
+}
+
+// importLegacyActionItems copies the entries of an action items message
+// written before action items were stored, so re-rendering doesn't drop them.
+func (s *IncidentService) importLegacyActionItems(ctx context.Context, incident *Incident) error {
+	pinned, err := s.getActionItemMessage(ctx, incident.ChannelID)
+	if err != nil || pinned == nil {
+		return err
+	}
+
+	for _, block := range pinned.Message.Blocks.BlockSet[min(1, len(pinned.Message.Blocks.BlockSet)):] {
+		section, ok := block.(*slack.SectionBlock)
+		if !ok || section.Text == nil {
+			continue
+		}
+
+		// Legacy lines look like "<@U123> - description".
+		item := ActionItem{IncidentID: incident.ID, Description: section.Text.Text}
+		if user, description, found := strings.Cut(section.Text.Text, "> - "); found && strings.HasPrefix(user, "<@") {
+			item.User = strings.TrimPrefix(user, "<@")
+			item.Description = description
+		}
+		if err := s.store.AddActionItem(ctx, &item); err != nil {
+			return err
+		}
+	}
+
+	incident.ActionItemsTS = pinned.Message.Timestamp
+	return s.store.UpdateIncident(ctx, incident)
+}
+
+func (s *IncidentService) getActionItemMessage(ctx context.Context, channelID string) (*slack.Item, error) {
+	items, err := s.slackService.ListPins(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		slog.WarnContext(ctx, "No pins found in channel", "channelID", channelID)
+		return nil, nil
+	}
+
+	for _, item := range items {
+		if strings.Contains(item.Message.Text, "*Action Items*") {
+			return &item, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// actionItemNumberFromBlockID extracts the item number from a checkbox block
+// rendered by actionItemBlocks.
+func actionItemNumberFromBlockID(blockID string) (int, bool) {
+	if !strings.HasPrefix(blockID, actionItemBlockPrefix) {
+		return 0, false
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(blockID, actionItemBlockPrefix))
+	return number, err == nil
+}
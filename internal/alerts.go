@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	alertFiring   = "firing"
+	alertResolved = "resolved"
+)
+
+// AlertmanagerWebhook is the body Prometheus Alertmanager posts to webhook
+// receivers (version 4).
+type AlertmanagerWebhook struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Receiver          string            `json:"receiver"`
+	Status            string            `json:"status"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+func (a Alert) name() string {
+	if name := a.Labels["alertname"]; name != "" {
+		return name
+	}
+	return a.Fingerprint
+}
+
+// summary is the alert's summary or description annotation, falling back to
+// its name.
+func (a Alert) summary() string {
+	for _, key := range []string{"summary", "description"} {
+		if text := strings.TrimSpace(a.Annotations[key]); text != "" {
+			return text
+		}
+	}
+	return a.name()
+}
+
+func (a Alert) timelineText(prefix string) string {
+	text := fmt.Sprintf("%s *%s*", prefix, a.name())
+	if summary := a.summary(); summary != a.name() {
+		text += ": " + summary
+	}
+	if a.GeneratorURL != "" {
+		text += fmt.Sprintf(" (<%s|source>)", a.GeneratorURL)
+	}
+	return text
+}
+
+// HandleAlertmanager routes an Alertmanager notification to incidents. Alerts
+// are tracked by fingerprint: a firing alert that isn't part of an open
+// incident joins the open incident of its alert group, or declares a new
+// incident when there is none. Repeat notifications for an alert are ignored,
+// and resolved alerts are noted on the timeline. Once every alert of an
+// incident has resolved, HAL suggests moving it to monitoring.
+func (s *IncidentService) HandleAlertmanager(ctx context.Context, hook AlertmanagerWebhook) error {
+	// Notifications for the same group are serialised so that two of them
+	// can't both declare an incident.
+	unlock := s.locks.Lock("alertmanager:" + hook.GroupKey)
+	defer unlock()
+
+	var firing, resolved []Alert
+	records := make(map[string]*IncidentAlert)
+	var incident *Incident
+	for _, alert := range hook.Alerts {
+		if alert.Fingerprint == "" {
+			slog.WarnContext(ctx, "Ignoring alert without a fingerprint", "alert", alert.name())
+			continue
+		}
+
+		record, open, err := s.trackedAlert(ctx, alert.Fingerprint)
+		if err != nil {
+			return err
+		}
+		if open != nil && incident == nil {
+			incident = open
+		}
+		if open == nil {
+			record = nil
+		}
+		records[alert.Fingerprint] = record
+
+		switch {
+		case alert.Status == alertResolved:
+			if record != nil && record.Status == alertFiring {
+				resolved = append(resolved, alert)
+			}
+		// An alert that fires again starts over, so a firing notification
+		// with the StartsAt already on record is a repeat, or a stale one
+		// delivered after the alert resolved.
+		case record == nil || !record.StartsAt.Equal(alert.StartsAt):
+			firing = append(firing, alert)
+		}
+	}
+
+	if len(firing) > 0 && incident == nil {
+		var err error
+		incident, err = s.groupIncident(ctx, hook.GroupKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(firing) > 0 && incident == nil {
+		config := s.config()
+		declared, err := s.DeclareIncident(ctx, IncidentDeclaration{
+			Description: alertDescription(hook, firing),
+			Severity:    s.alertSeverity(firing),
+			Status:      config.Taxonomy.InitialStatus(),
+			Members:     config.Alertmanager.Invite,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare incident for alerts: %w", err)
+		}
+		slog.InfoContext(ctx, "Declared incident from Alertmanager", "incidentID", declared.ID, "groupKey", hook.GroupKey, "alerts", len(firing))
+		incident = declared
+	}
+
+	for _, alert := range firing {
+		// Alerts already routed to an incident stay with it when they fire
+		// again; everything else goes to the group's incident.
+		incidentID := incident.ID
+		if record := records[alert.Fingerprint]; record != nil {
+			incidentID = record.IncidentID
+		}
+		if err := s.recordAlert(ctx, incidentID, hook.GroupKey, alert, "Alert firing:"); err != nil {
+			return err
+		}
+	}
+
+	affected := make(map[string]bool)
+	for _, alert := range resolved {
+		incidentID := records[alert.Fingerprint].IncidentID
+		if err := s.recordAlert(ctx, incidentID, hook.GroupKey, alert, "Alert resolved:"); err != nil {
+			return err
+		}
+		affected[incidentID] = true
+	}
+
+	for incidentID := range affected {
+		if err := s.suggestMonitoring(ctx, incidentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackedAlert returns the stored alert with the given fingerprint and, if it
+// belongs to an incident that is still open, that incident.
+func (s *IncidentService) trackedAlert(ctx context.Context, fingerprint string) (*IncidentAlert, *Incident, error) {
+	record, err := s.store.GetAlert(ctx, fingerprint)
+	if errors.Is(err, ErrAlertNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	incident, err := s.store.GetIncident(ctx, record.IncidentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load incident for alert: %w", err)
+	}
+	if incident.Status == StatusResolved {
+		return record, nil, nil
+	}
+	return record, incident, nil
+}
+
+// groupIncident returns the most recent open incident with alerts from the
+// given group, if any.
+func (s *IncidentService) groupIncident(ctx context.Context, groupKey string) (*Incident, error) {
+	if groupKey == "" {
+		return nil, nil
+	}
+	alerts, err := s.store.ListAlerts(ctx, AlertFilter{GroupKey: groupKey})
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		incident, err := s.store.GetIncident(ctx, alert.IncidentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load incident for alert group: %w", err)
+		}
+		if incident.Status != StatusResolved {
+			return incident, nil
+		}
+	}
+	return nil, nil
+}
+
+// recordAlert stores the alert against the incident and adds it to the
+// incident's timeline.
+func (s *IncidentService) recordAlert(ctx context.Context, incidentID, groupKey string, alert Alert, prefix string) error {
+	return s.withIncidentID(ctx, incidentID, func(incident *Incident) error {
+		err := s.store.SaveAlert(ctx, &IncidentAlert{
+			Fingerprint: alert.Fingerprint,
+			IncidentID:  incident.ID,
+			GroupKey:    groupKey,
+			Name:        alert.name(),
+			Status:      alert.Status,
+			StartsAt:    alert.StartsAt,
+		})
+		if err != nil {
+			return err
+		}
+		return s.addTimelineItem(ctx, incident, "", alert.timelineText(prefix))
+	})
+}
+
+// suggestMonitoring posts a suggestion to move the incident to monitoring
+// once none of its alerts are firing. Taxonomies without a monitoring status
+// get no suggestion.
+func (s *IncidentService) suggestMonitoring(ctx context.Context, incidentID string) error {
+	monitoring, ok := s.config().Taxonomy.Status(StatusMonitoring)
+	if !ok {
+		return nil
+	}
+	alerts, err := s.store.ListAlerts(ctx, AlertFilter{IncidentID: incidentID})
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		if alert.Status == alertFiring {
+			return nil
+		}
+	}
+
+	incident, err := s.store.GetIncident(ctx, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+	if incident.Status == StatusResolved || incident.Status == StatusMonitoring {
+		return nil
+	}
+
+	text := fmt.Sprintf(":large_green_circle: All alerts for this incident have resolved. If the fix is in, consider moving the incident to *%s* with `/incident update`.", monitoring.Label)
+	_, err = s.slackService.PostMessage(ctx, incident.ChannelID, []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post monitoring suggestion: %w", err)
+	}
+	return nil
+}
+
+// alertSeverity maps the alerts' severity labels to the most severe matching
+// incident severity.
+func (s *IncidentService) alertSeverity(alerts []Alert) Severity {
+	config := s.config()
+	taxonomy := config.Taxonomy
+
+	severity := config.Alertmanager.DefaultSeverity
+	if _, ok := taxonomy.Severity(severity); !ok {
+		severity = taxonomy.Severities[len(taxonomy.Severities)-1].ID
+	}
+	for _, alert := range alerts {
+		value := alert.Labels[config.Alertmanager.SeverityLabel]
+		mapped, ok := config.Alertmanager.Severities[value]
+		if _, known := taxonomy.Severity(mapped); !ok || !known {
+			mapped, ok = taxonomy.ParseSeverity(value)
+		}
+		if ok && taxonomy.SeverityRank(mapped) < taxonomy.SeverityRank(severity) {
+			severity = mapped
+		}
+	}
+	return severity
+}
+
+// alertDescription describes an incident declared for alerts: the group's
+// common summary if it has one, otherwise the first alert's.
+func alertDescription(hook AlertmanagerWebhook, alerts []Alert) string {
+	for _, key := range []string{"summary", "description"} {
+		if text := strings.TrimSpace(hook.CommonAnnotations[key]); text != "" {
+			return text
+		}
+	}
+	description := alerts[0].summary()
+	if len(alerts) > 1 {
+		description += fmt.Sprintf(" (and %d more alerts)", len(alerts)-1)
+	}
+	return description
+}
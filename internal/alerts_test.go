@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (a *testApp) alertmanager(t *testing.T, token string, hook AlertmanagerWebhook) int {
+	t.Helper()
+	body, err := json.Marshal(hook)
+	if err != nil {
+		t.Fatalf("marshal alerts: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/alertmanager", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func (a *testApp) alertTimeline(t *testing.T, incidentID string) []string {
+	t.Helper()
+	items, err := a.store.ListTimelineItems(context.Background(), incidentID)
+	if err != nil {
+		t.Fatalf("ListTimelineItems: %v", err)
+	}
+	var alerts []string
+	for _, item := range items {
+		if strings.HasPrefix(item.Message, "Alert ") {
+			alerts = append(alerts, item.Message)
+		}
+	}
+	return alerts
+}
+
+func TestAlertmanager(t *testing.T) {
+	app := newTestApp(t)
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	alert := func(fingerprint, name, severity, status string) Alert {
+		return Alert{
+			Status:       status,
+			Labels:       map[string]string{"alertname": name, "severity": severity},
+			Annotations:  map[string]string{"summary": name + " is failing"},
+			StartsAt:     started,
+			GeneratorURL: "http://prometheus/graph",
+			Fingerprint:  fingerprint,
+		}
+	}
+	hook := func(alerts ...Alert) AlertmanagerWebhook {
+		return AlertmanagerWebhook{
+			Version:           "4",
+			GroupKey:          `{}:{alertname="checkout"}`,
+			CommonAnnotations: map[string]string{"summary": "Checkout errors"},
+			Alerts:            alerts,
+		}
+	}
+
+	if code := app.alertmanager(t, "wrong", hook(alert("f1", "CheckoutErrors", "critical", alertFiring))); code != http.StatusUnauthorized {
+		t.Fatalf("bad token: status %d", code)
+	}
+
+	// The first notification declares an incident at the most severe mapped
+	// severity.
+	firing := hook(alert("f1", "CheckoutErrors", "warning", alertFiring), alert("f2", "CheckoutLatency", "critical", alertFiring))
+	if code := app.alertmanager(t, testAlertmanagerToken, firing); code != http.StatusOK {
+		t.Fatalf("firing: status %d", code)
+	}
+	eventually(t, "incident declared", func() bool {
+		incidents, _ := app.store.ListIncidents(context.Background(), IncidentFilter{})
+		return len(incidents) == 1 && len(app.alertTimeline(t, incidents[0].ID)) == 2
+	})
+	incident := app.incident()
+	if incident.Description != "Checkout errors" || incident.Severity != SeveritySev1 || incident.Status != StatusInvestigating {
+		t.Errorf("declared incident = %+v", incident)
+	}
+	if !slices.Contains(app.slack.Channel(incident.ChannelID).Members, "U0ONCALL") {
+		t.Errorf("invite list not added to channel: %v", app.slack.Channel(incident.ChannelID).Members)
+	}
+
+	// Repeat notifications are ignored; new alerts in the group join the
+	// same incident.
+	app.alertmanager(t, testAlertmanagerToken, firing)
+	app.alertmanager(t, testAlertmanagerToken, hook(alert("f3", "CheckoutQueue", "warning", alertFiring)))
+	eventually(t, "new alert added", func() bool { return len(app.alertTimeline(t, incident.ID)) == 3 })
+
+	app.alertmanager(t, testAlertmanagerToken, hook(alert("f1", "CheckoutErrors", "warning", alertResolved), alert("f2", "CheckoutLatency", "critical", alertResolved)))
+	eventually(t, "alerts resolved", func() bool { return len(app.alertTimeline(t, incident.ID)) == 5 })
+	app.alertmanager(t, testAlertmanagerToken, hook(alert("f3", "CheckoutQueue", "warning", alertResolved)))
+	eventually(t, "monitoring suggestion", func() bool {
+		for _, call := range app.slack.Calls("chat.postMessage") {
+			if call.Form.Get("channel") == incident.ChannelID && strings.Contains(call.Form.Get("blocks"), "All alerts for this incident have resolved") {
+				return true
+			}
+		}
+		return false
+	})
+
+	timeline := app.alertTimeline(t, incident.ID)
+	want := []string{
+		"Alert firing: *CheckoutErrors*: CheckoutErrors is failing (<http://prometheus/graph|source>)",
+		"Alert firing: *CheckoutLatency*: CheckoutLatency is failing (<http://prometheus/graph|source>)",
+		"Alert firing: *CheckoutQueue*: CheckoutQueue is failing (<http://prometheus/graph|source>)",
+		"Alert resolved: *CheckoutErrors*: CheckoutErrors is failing (<http://prometheus/graph|source>)",
+		"Alert resolved: *CheckoutLatency*: CheckoutLatency is failing (<http://prometheus/graph|source>)",
+		"Alert resolved: *CheckoutQueue*: CheckoutQueue is failing (<http://prometheus/graph|source>)",
+	}
+	if !slices.Equal(timeline, want) {
+		t.Errorf("alert timeline = %q", timeline)
+	}
+
+	// Once the incident is resolved, the alert firing again declares a new
+	// incident.
+	app.command(t, incident.ChannelID, "resolve")
+	eventually(t, "incident resolved", func() bool { return app.incident().Status == StatusResolved })
+	again := alert("f1", "CheckoutErrors", "warning", alertFiring)
+	again.StartsAt = started.Add(time.Hour)
+	app.alertmanager(t, testAlertmanagerToken, hook(again))
+	eventually(t, "second incident", func() bool {
+		incidents, _ := app.store.ListIncidents(context.Background(), IncidentFilter{})
+		return len(incidents) == 2
+	})
+}
@@ -0,0 +1,461 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The REST API lets tools without a Slack trigger ID drive incidents. Requests
+// may name the Slack user acting with user_id; otherwise changes are
+// attributed to nobody. Reads take user_id as a query parameter and record it
+// in the audit log of the confidential incidents they return.
+
+type createIncidentRequest struct {
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"`
+	Status      Status   `json:"status"`
+	CommanderID string   `json:"commander_id"`
+	CommsRepID  string   `json:"comms_rep_id"`
+	Members     []string `json:"members"`
+	UserID      string   `json:"user_id"`
+	// Confidential declares the incident in a private channel.
+	Confidential bool `json:"confidential"`
+}
+
+// updateIncidentRequest changes the fields that are set and leaves the rest.
+// Set a role to "" to clear it.
+type updateIncidentRequest struct {
+	Status      *Status `json:"status"`
+	Severity    *string `json:"severity"`
+	CommanderID *string `json:"commander_id"`
+	CommsRepID  *string `json:"comms_rep_id"`
+	UserID      string  `json:"user_id"`
+	// Roles assigns any role in the taxonomy by ID.
+	Roles map[string]string `json:"roles"`
+}
+
+type timelineRequest struct {
+	Message string `json:"message"`
+	UserID  string `json:"user_id"`
+}
+
+type actionItemRequest struct {
+	Description string `json:"description"`
+	UserID      string `json:"user_id"`
+	Assignee    string `json:"assignee"`
+}
+
+type updateActionItemRequest struct {
+	Completed bool   `json:"completed"`
+	UserID    string `json:"user_id"`
+}
+
+type resolveRequest struct {
+	Message string `json:"message"`
+	UserID  string `json:"user_id"`
+}
+
+// errInvalidRequest marks API errors caused by the request rather than HAL.
+var errInvalidRequest = errors.New("invalid request")
+
+func invalidRequest(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errInvalidRequest, fmt.Sprintf(format, args...))
+}
+
+// apiError responds with the status code matching err.
+func apiError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errInvalidRequest), errors.Is(err, ErrUnknownRole):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrIncidentNotFound), errors.Is(err, ErrActionItemNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrIncidentNotResolved), errors.Is(err, ErrChannelNamesExhausted):
+		status = http.StatusConflict
+	case errors.Is(err, ErrIncidentConfidential):
+		status = http.StatusForbidden
+	default:
+		slog.ErrorContext(c.Request.Context(), "API request failed", "method", c.Request.Method, "path", c.FullPath(), "error", err)
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// auditAPIReads records that the request read incidents, for those that are
+// confidential.
+func auditAPIReads(c *gin.Context, incidentService *IncidentService, incidents ...*Incident) {
+	auditReads(c.Request.Context(), incidentService.store, incidents, c.Query("user_id"), c.Request.Method+" "+c.Request.URL.Path)
+}
+
+// bindJSON decodes the request body, responding with a 400 if it is invalid.
+func bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		apiError(c, invalidRequest("%v", err))
+		return false
+	}
+	return true
+}
+
+// apiIncident loads the incident named in the URL.
+func apiIncident(c *gin.Context, incidentService *IncidentService) (*Incident, bool) {
+	incident, err := incidentService.GetIncident(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		apiError(c, err)
+		return nil, false
+	}
+	return incident, true
+}
+
+func CreateIncidentAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createIncidentRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+
+		taxonomy := incidentService.config().Taxonomy
+		req.Description = strings.TrimSpace(req.Description)
+		if req.Description == "" {
+			apiError(c, invalidRequest("description is required"))
+			return
+		}
+		severity, ok := taxonomy.ParseSeverity(req.Severity)
+		if !ok {
+			apiError(c, invalidRequest("unknown severity %q", req.Severity))
+			return
+		}
+		if req.Status == "" {
+			req.Status = taxonomy.InitialStatus()
+		}
+		if _, ok := taxonomy.Status(req.Status); !ok || req.Status == StatusResolved {
+			apiError(c, invalidRequest("status must be one of %v", taxonomy.OpenStatuses()))
+			return
+		}
+
+		incident, err := incidentService.DeclareIncident(c.Request.Context(), IncidentDeclaration{
+			Description:  req.Description,
+			Severity:     severity,
+			Status:       req.Status,
+			CommanderID:  req.CommanderID,
+			CommsRepID:   req.CommsRepID,
+			Members:      req.Members,
+			DeclaredBy:   req.UserID,
+			Confidential: req.Confidential,
+		})
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, incident)
+	}
+}
+
+// ListIncidentsAPIHandler lists incidents, newest first. The status query
+// parameter is "open" (the default), "all", "resolved" or a status ID;
+// severity and limit are optional.
+func ListIncidentsAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 0
+		if query := c.Query("limit"); query != "" {
+			n, err := strconv.Atoi(query)
+			if err != nil || n < 1 {
+				apiError(c, invalidRequest("limit must be a positive number"))
+				return
+			}
+			limit = n
+		}
+		filter, err := queryFilter(incidentService.config().Taxonomy, c.Query("status"), c.Query("severity"), limit)
+		if err != nil {
+			apiError(c, invalidRequest("%v", err))
+			return
+		}
+
+		incidents, err := incidentService.ListIncidents(c.Request.Context(), filter)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		if incidents == nil {
+			incidents = []*Incident{}
+		}
+		auditAPIReads(c, incidentService, incidents...)
+		c.JSON(http.StatusOK, gin.H{"incidents": incidents})
+	}
+}
+
+func GetIncidentAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+		auditAPIReads(c, incidentService, incident)
+		c.JSON(http.StatusOK, incident)
+	}
+}
+
+// UpdateIncidentAPIHandler applies the same changes as the update modal.
+func UpdateIncidentAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req updateIncidentRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+
+		taxonomy := incidentService.config().Taxonomy
+		status, severity := incident.Status, incident.Severity
+		if req.Status != nil {
+			if _, ok := taxonomy.Status(*req.Status); !ok {
+				apiError(c, invalidRequest("unknown status %q", *req.Status))
+				return
+			}
+			status = *req.Status
+		}
+		if req.Severity != nil {
+			severity, ok = taxonomy.ParseSeverity(*req.Severity)
+			if !ok {
+				apiError(c, invalidRequest("unknown severity %q", *req.Severity))
+				return
+			}
+		}
+		roles := make(map[string]string, len(req.Roles)+2)
+		for roleID, userID := range req.Roles {
+			if _, ok := taxonomy.Role(roleID); !ok {
+				apiError(c, invalidRequest("unknown role %q", roleID))
+				return
+			}
+			roles[roleID] = userID
+		}
+		if req.CommanderID != nil {
+			roles[RoleCommander] = *req.CommanderID
+		}
+		if req.CommsRepID != nil {
+			roles[RoleComms] = *req.CommsRepID
+		}
+
+		ctx := c.Request.Context()
+		err := incidentService.UpdateIncidentDetails(ctx, incident.ChannelID, req.UserID, status, severity, roles)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		incident, err = incidentService.GetIncident(ctx, incident.ID)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, incident)
+	}
+}
+
+func ResolveIncidentAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req resolveRequest
+		if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+			return
+		}
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+
+		ctx := c.Request.Context()
+		err := incidentService.ResolveIncident(ctx, incident.ChannelID, req.UserID, strings.TrimSpace(req.Message))
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		incident, err = incidentService.GetIncident(ctx, incident.ID)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, incident)
+	}
+}
+
+func ListTimelineAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+		auditAPIReads(c, incidentService, incident)
+		respondTimeline(c, incidentService, incident, http.StatusOK)
+	}
+}
+
+func AddTimelineAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req timelineRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		if req.Message = strings.TrimSpace(req.Message); req.Message == "" {
+			apiError(c, invalidRequest("message is required"))
+			return
+		}
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+
+		if err := incidentService.AddTimelineItem(c.Request.Context(), incident.ChannelID, req.UserID, req.Message); err != nil {
+			apiError(c, err)
+			return
+		}
+		respondTimeline(c, incidentService, incident, http.StatusCreated)
+	}
+}
+
+func respondTimeline(c *gin.Context, incidentService *IncidentService, incident *Incident, status int) {
+	items, err := incidentService.ListTimeline(c.Request.Context(), incident.ID)
+	if err != nil {
+		apiError(c, err)
+		return
+	}
+	if items == nil {
+		items = []TimelineItem{}
+	}
+	c.JSON(status, gin.H{"timeline": items})
+}
+
+func ListActionItemsAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+		auditAPIReads(c, incidentService, incident)
+		respondActionItems(c, incidentService, incident, http.StatusOK)
+	}
+}
+
+// AddActionItemAPIHandler adds an action item. Adding a description that is
+// already listed changes nothing.
+func AddActionItemAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req actionItemRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		if req.Description = strings.TrimSpace(req.Description); req.Description == "" {
+			apiError(c, invalidRequest("description is required"))
+			return
+		}
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+
+		if err := incidentService.AddActionItem(c.Request.Context(), incident.ChannelID, req.UserID, req.Assignee, req.Description); err != nil {
+			apiError(c, err)
+			return
+		}
+		respondActionItems(c, incidentService, incident, http.StatusCreated)
+	}
+}
+
+// UpdateActionItemAPIHandler marks an action item, by number, as done or not
+// done.
+func UpdateActionItemAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req updateActionItemRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		number, err := strconv.Atoi(c.Param("number"))
+		if err != nil {
+			apiError(c, invalidRequest("action item number must be a number"))
+			return
+		}
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+
+		item, err := incidentService.SetActionItemCompleted(c.Request.Context(), incident.ChannelID, req.UserID, number, req.Completed)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, item)
+	}
+}
+
+func respondActionItems(c *gin.Context, incidentService *IncidentService, incident *Incident, status int) {
+	items, err := incidentService.ListActionItems(c.Request.Context(), incident.ChannelID)
+	if err != nil {
+		apiError(c, err)
+		return
+	}
+	if items == nil {
+		items = []ActionItem{}
+	}
+	c.JSON(status, gin.H{"action_items": items})
+}
+
+// PostmortemAPIHandler returns the incident's postmortem as Markdown.
+func PostmortemAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+		doc, err := incidentService.Postmortem(c.Request.Context(), incident.ID)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		auditAPIReads(c, incidentService, incident)
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(doc))
+	}
+}
+
+// AuditLogAPIHandler returns the access-control decisions recorded for a
+// confidential incident.
+func AuditLogAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		incident, ok := apiIncident(c, incidentService)
+		if !ok {
+			return
+		}
+		entries, err := incidentService.ListAuditLog(c.Request.Context(), incident.ID)
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		if entries == nil {
+			entries = []AuditEntry{}
+		}
+		c.JSON(http.StatusOK, gin.H{"audit_log": entries})
+	}
+}
+
+// StatsAPIHandler reports incident statistics for the period query parameter,
+// which takes the same values as `/incident stats`.
+func StatsAPIHandler(incidentService *IncidentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		period, err := parseStatsPeriod(c.Query("period"))
+		if err != nil {
+			apiError(c, invalidRequest("%v", err))
+			return
+		}
+		stats, err := incidentService.Stats(c.Request.Context(), period, time.Now())
+		if err != nil {
+			apiError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// api calls the REST API and decodes the JSON response into out, if given.
+func (a *testApp) api(t *testing.T, method, path, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: bad response %s: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAPI(t *testing.T) {
+	app := newTestApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/incidents", nil)
+	rec := httptest.NewRecorder()
+	app.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated request: status %d", rec.Code)
+	}
+
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Deploy failed", "severity": "bogus"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("bad severity: status %d", code)
+	}
+
+	var incident Incident
+	code := app.api(t, http.MethodPost, "/incidents", `{"description": "Deploy failed", "severity": "sev2", "commander_id": "U0IC", "user_id": "U0DEPLOY"}`, &incident)
+	if code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+	if incident.ID == "" || incident.Severity != SeveritySev2 || incident.Status != StatusInvestigating || incident.CommanderID != "U0IC" || incident.ChannelID == "" {
+		t.Errorf("created incident = %+v", incident)
+	}
+	path := "/incidents/" + incident.ID
+
+	var got Incident
+	if code := app.api(t, http.MethodGet, path, "", &got); code != http.StatusOK || got.Description != "Deploy failed" {
+		t.Errorf("get: status %d, incident %+v", code, got)
+	}
+	if code := app.api(t, http.MethodGet, "/incidents/INC-999", "", nil); code != http.StatusNotFound {
+		t.Errorf("get missing incident: status %d", code)
+	}
+
+	var list struct{ Incidents []Incident }
+	if app.api(t, http.MethodGet, "/incidents?severity=SEV-2", "", &list); len(list.Incidents) != 1 {
+		t.Errorf("list SEV-2 = %+v", list.Incidents)
+	}
+	if app.api(t, http.MethodGet, "/incidents?status=resolved", "", &list); len(list.Incidents) != 0 {
+		t.Errorf("list resolved = %+v", list.Incidents)
+	}
+
+	var updated Incident
+	code = app.api(t, http.MethodPatch, path, `{"status": "Fixing", "comms_rep_id": "U0COMMS"}`, &updated)
+	if code != http.StatusOK || updated.Status != StatusFixing || updated.Severity != SeveritySev2 || updated.CommanderID != "U0IC" || updated.CommsRepID != "U0COMMS" {
+		t.Errorf("patch: status %d, incident %+v", code, updated)
+	}
+
+	var timeline struct{ Timeline []TimelineItem }
+	code = app.api(t, http.MethodPost, path+"/timeline", `{"message": "Rolled back", "user_id": "U0DEPLOY"}`, &timeline)
+	if code != http.StatusCreated || len(timeline.Timeline) != 3 || timeline.Timeline[2].Message != "Rolled back" || timeline.Timeline[2].User != "U0DEPLOY" {
+		t.Errorf("add timeline: status %d, timeline %+v", code, timeline.Timeline)
+	}
+	if !strings.Contains(app.slack.PinnedText(incident.ChannelID), "Rolled back") {
+		t.Error("pinned timeline not updated")
+	}
+
+	var items struct {
+		ActionItems []ActionItem `json:"action_items"`
+	}
+	code = app.api(t, http.MethodPost, path+"/action-items", `{"description": "Add deploy canary"}`, &items)
+	if code != http.StatusCreated || len(items.ActionItems) != 2 || items.ActionItems[1].Description != "Add deploy canary" {
+		t.Errorf("add action item: status %d, items %+v", code, items.ActionItems)
+	}
+	var item ActionItem
+	code = app.api(t, http.MethodPatch, path+"/action-items/2", `{"completed": true, "user_id": "U0DEPLOY"}`, &item)
+	if code != http.StatusOK || !item.Completed || item.CompletedBy != "U0DEPLOY" {
+		t.Errorf("complete action item: status %d, item %+v", code, item)
+	}
+	if code := app.api(t, http.MethodPatch, path+"/action-items/9", `{"completed": true}`, nil); code != http.StatusNotFound {
+		t.Errorf("complete missing action item: status %d", code)
+	}
+
+	var resolved Incident
+	code = app.api(t, http.MethodPost, path+"/resolve", `{"message": "Rollback complete"}`, &resolved)
+	if code != http.StatusOK || resolved.Status != StatusResolved || resolved.ResolvedAt == nil {
+		t.Errorf("resolve: status %d, incident %+v", code, resolved)
+	}
+	if app.api(t, http.MethodGet, "/incidents?status=resolved", "", &list); len(list.Incidents) != 1 {
+		t.Errorf("list resolved after resolving = %+v", list.Incidents)
+	}
+}
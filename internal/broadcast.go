@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	joinIncidentActionID = "join_incident"
+	// resolvedColor replaces the severity colour on the cards of resolved
+	// incidents.
+	resolvedColor = "#2eb67d"
+)
+
+// broadcastCard renders the announcement card for an incident: its severity,
+// status, description and roles, with a button to join the incident channel.
+func (s *IncidentService) broadcastCard(incident *Incident) (string, []slack.Block) {
+	taxonomy := s.config().Taxonomy
+	severity, _ := taxonomy.Severity(incident.Severity)
+	if severity.Label == "" {
+		severity.Label = string(incident.Severity)
+	}
+	status, _ := taxonomy.Status(incident.Status)
+	if status.Label == "" {
+		status.Label = string(incident.Status)
+	}
+
+	color := severity.Color
+	headline := fmt.Sprintf(":rotating_light: *%s incident:* %s", severity.Label, incident.Description)
+	if incident.Status == StatusResolved {
+		color = resolvedColor
+		headline = fmt.Sprintf(":white_check_mark: *Resolved:* %s", incident.Description)
+	}
+
+	role := func(userID string) string {
+		if userID == "" {
+			return "_Unassigned_"
+		}
+		return fmt.Sprintf("<@%s>", userID)
+	}
+	fields := []*slack.TextBlockObject{
+		slack.NewTextBlockObject("mrkdwn", "*Severity*\n"+severity.Label, false, false),
+		slack.NewTextBlockObject("mrkdwn", "*Status*\n"+status.Label, false, false),
+		slack.NewTextBlockObject("mrkdwn", "*Incident Commander*\n"+role(incident.CommanderID), false, false),
+		slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*Channel*\n<#%s>", incident.ChannelID), false, false),
+	}
+
+	footer := []string{incident.ID}
+	if incident.CreatedBy != "" {
+		footer = append(footer, fmt.Sprintf("declared by <@%s>", incident.CreatedBy))
+	}
+
+	join := slack.NewButtonBlockElement(joinIncidentActionID, incident.ID, slack.NewTextBlockObject("plain_text", "Join channel", false, false))
+	return color, []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", headline, false, false), fields, nil),
+		slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", strings.Join(footer, ", "), false, false)),
+		slack.NewActionBlock("", join),
+	}
+}
+
+// postBroadcast announces a new incident in the broadcast channel, if one is
+// configured and the incident isn't confidential. By now the incident
+// channel is open and responders are being paged into it, so a failed
+// announcement is logged rather than failing the declaration.
+func (s *IncidentService) postBroadcast(ctx context.Context, incidentID string) {
+	channel := s.config().BroadcastChannel
+	if channel == "" {
+		return
+	}
+	err := s.withIncidentID(ctx, incidentID, func(incident *Incident) error {
+		if incident.Confidential {
+			s.audit(ctx, incident, AuditBroadcastSkipped, incident.CreatedBy, false, channel)
+			return nil
+		}
+		color, blocks := s.broadcastCard(incident)
+		channelID, ts, err := s.slackService.PostAttachment(ctx, channel, color, blocks)
+		if err != nil {
+			return err
+		}
+		incident.BroadcastChannelID = channelID
+		incident.BroadcastTS = ts
+		return s.store.UpdateIncident(ctx, incident)
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to announce incident", "incidentID", incidentID, "channel", channel, "error", err)
+	}
+}
+
+// updateBroadcast refreshes the incident's announcement card in place. The
+// caller must hold the incident lock.
+func (s *IncidentService) updateBroadcast(ctx context.Context, incident *Incident) {
+	if incident.BroadcastTS == "" {
+		return
+	}
+	color, blocks := s.broadcastCard(incident)
+	err := s.slackService.UpdateAttachment(ctx, incident.BroadcastChannelID, incident.BroadcastTS, color, blocks)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to update incident announcement", "incidentID", incident.ID, "error", err)
+	}
+}
+
+// JoinIncident adds a user to the incident channel, for the join button on
+// announcement cards. Only people involved in a confidential incident may
+// join it.
+func (s *IncidentService) JoinIncident(ctx context.Context, incidentID, userID string) (*Incident, error) {
+	var joined *Incident
+	err := s.withIncidentID(ctx, incidentID, func(incident *Incident) error {
+		if incident.Confidential {
+			allowed := isInvolved(incident, userID)
+			s.audit(ctx, incident, AuditJoin, userID, allowed, "")
+			if !allowed {
+				return ErrIncidentConfidential
+			}
+		}
+		if incident.ArchivedAt != nil {
+			return fmt.Errorf("the channel of %s is archived", incident.ID)
+		}
+		err := s.slackService.InviteUsersToChannel(ctx, incident.ChannelID, userID)
+		if err != nil && !strings.Contains(err.Error(), "already_in_channel") {
+			return err
+		}
+		if !slices.Contains(incident.Members, userID) {
+			incident.Members = append(incident.Members, userID)
+			if err := s.store.UpdateIncident(ctx, incident); err != nil {
+				return fmt.Errorf("failed to store incident members: %w", err)
+			}
+		}
+		joined = incident
+		return nil
+	})
+	return joined, err
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestBroadcastCard(t *testing.T) {
+	app := newTestApp(t)
+	app.config.BroadcastChannel = "C0BROADCAST"
+
+	app.interaction(t, createIncidentSubmission("V1400", "Search is down", "SEV-2"))
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	incident := app.incident()
+
+	posts := app.slack.Calls("chat.postMessage")
+	var card fakeSlackCall
+	for _, call := range posts {
+		if call.Form.Get("channel") == "C0BROADCAST" {
+			card = call
+		}
+	}
+	attachments := card.Form.Get("attachments")
+	for _, want := range []string{"*SEV-2 incident:* Search is down", "#" + incident.ChannelID, "#f8e71c", joinIncidentActionID} {
+		if !strings.Contains(attachments, want) {
+			t.Errorf("announcement missing %q: %s", want, attachments)
+		}
+	}
+	if incident.BroadcastChannelID != "C0BROADCAST" {
+		t.Errorf("announcement channel = %q", incident.BroadcastChannelID)
+	}
+
+	// Updates and resolution edit the same message.
+	path := "/incidents/" + incident.ID
+	if code := app.api(t, http.MethodPatch, path, `{"severity": "SEV-1", "commander_id": "U0IC"}`, nil); code != http.StatusOK {
+		t.Fatalf("patch: status %d", code)
+	}
+	app.command(t, incident.ChannelID, "resolve")
+	eventually(t, "announcement resolved", func() bool {
+		updates := app.slack.Calls("chat.update")
+		for _, call := range updates {
+			if call.Form.Get("channel") == "C0BROADCAST" && strings.Contains(call.Form.Get("attachments"), "*Resolved:* Search is down") {
+				return true
+			}
+		}
+		return false
+	})
+	var edits []string
+	for _, call := range app.slack.Calls("chat.update") {
+		if call.Form.Get("channel") == "C0BROADCAST" {
+			if call.Form.Get("ts") != incident.BroadcastTS {
+				t.Errorf("updated message %s, want %s", call.Form.Get("ts"), incident.BroadcastTS)
+			}
+			edits = append(edits, call.Form.Get("attachments"))
+		}
+	}
+	if len(edits) != 2 || !strings.Contains(edits[0], "*SEV-1 incident:*") || !strings.Contains(edits[0], "\\u003c@U0IC\\u003e") {
+		t.Errorf("announcement edits = %v", edits)
+	}
+
+	// The join button invites whoever clicks it.
+	var click slack.InteractionCallback
+	click.Type = slack.InteractionTypeBlockActions
+	click.TriggerID = "T-join"
+	click.User = slack.User{ID: "U9"}
+	click.Channel = slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C0BROADCAST"}}}
+	click.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: joinIncidentActionID, Value: incident.ID}}
+	app.interaction(t, click)
+	eventually(t, "join", func() bool { return slices.Contains(app.incident().Members, "U9") })
+	if members := app.slack.Channel(incident.ChannelID).Members; !slices.Contains(members, "U9") {
+		t.Errorf("channel members = %v", members)
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultChannelTemplate follows ChannelPrefix in incident channel names.
+	defaultChannelTemplate = "{date}-{seq}"
+	// maxChannelNameLength is Slack's limit on channel names.
+	maxChannelNameLength = 80
+	// maxChannelNameAttempts bounds how many sequence numbers are tried when
+	// names are already taken.
+	maxChannelNameAttempts = 10
+)
+
+// ErrChannelNamesExhausted is returned when no free channel name can be found
+// for a new incident.
+var ErrChannelNamesExhausted = errors.New("no free incident channel name")
+
+var (
+	channelTemplateToken = regexp.MustCompile(`\{([^{}]*)\}`)
+	channelNameInvalid   = regexp.MustCompile(`[^a-z0-9_-]+`)
+	channelNameDashes    = regexp.MustCompile(`-{2,}`)
+)
+
+// channelTemplateTokens are the tokens channel templates may use.
+var channelTemplateTokens = []string{"date", "seq", "sev", "slug(description)", "id"}
+
+// channelNameData is what channel templates are rendered with.
+type channelNameData struct {
+	Date        time.Time
+	Seq         int
+	Severity    string
+	Description string
+	ID          string
+}
+
+// validateChannelTemplate checks that a template only uses known tokens and
+// has a {seq} or {id} to keep names unique. Empty means the default.
+func validateChannelTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	var unique bool
+	for _, match := range channelTemplateToken.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case "seq", "id":
+			unique = true
+		case "date", "sev", "slug(description)":
+		default:
+			return fmt.Errorf("unknown token {%s}, use %s", match[1], strings.Join(channelTemplateTokens, ", "))
+		}
+	}
+	if !unique {
+		return errors.New("must contain {seq} or {id}")
+	}
+	return nil
+}
+
+// channelTemplateUsesSeq reports whether names from template change with the
+// sequence, so a taken name is worth retrying with the next number.
+func channelTemplateUsesSeq(template string) bool {
+	return strings.Contains(template, "{seq}")
+}
+
+// renderChannelName builds a channel name from prefix and template. The
+// result is a valid Slack channel name; the description slug is shortened to
+// keep it within Slack's length limit.
+func renderChannelName(prefix, template string, data channelNameData) string {
+	expand := func(slug string) string {
+		return sanitizeChannelName(prefix + channelTemplateToken.ReplaceAllStringFunc(template, func(token string) string {
+			switch strings.Trim(token, "{}") {
+			case "date":
+				return data.Date.Format("20060102")
+			case "seq":
+				return strconv.Itoa(data.Seq)
+			case "sev":
+				return data.Severity
+			case "slug(description)":
+				return slug
+			case "id":
+				return data.ID
+			}
+			return ""
+		}))
+	}
+
+	// Measure around a one character slug, since an empty one would let the
+	// separators either side of it collapse.
+	slug := sanitizeChannelName(data.Description)
+	if room := maxChannelNameLength - len(expand("x")) + 1; len(slug) > room {
+		slug = strings.TrimRight(slug[:max(room, 0)], "-_")
+	}
+	name := expand(slug)
+	if len(name) > maxChannelNameLength {
+		name = strings.TrimRight(name[:maxChannelNameLength], "-_")
+	}
+	return name
+}
+
+// sanitizeChannelName lower-cases s and replaces runs of characters Slack
+// doesn't allow in channel names with a single hyphen.
+func sanitizeChannelName(s string) string {
+	s = channelNameInvalid.ReplaceAllString(strings.ToLower(s), "-")
+	s = channelNameDashes.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestRenderChannelName(t *testing.T) {
+	data := channelNameData{
+		Date:        time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC),
+		Seq:         42,
+		Severity:    "SEV-1",
+		Description: "Checkout API: 500s for EU users!!",
+		ID:          "INC-7",
+	}
+	for _, tt := range []struct {
+		prefix, template, want string
+	}{
+		{"incident-", defaultChannelTemplate, "incident-20260309-42"},
+		{"inc-", "{sev}-{slug(description)}-{seq}", "inc-sev-1-checkout-api-500s-for-eu-users-42"},
+		{"", "{id}_{date}", "inc-7_20260309"},
+		{"incident-", "{slug(description)}-{seq}", "incident-checkout-api-500s-for-eu-users-42"},
+	} {
+		if got := renderChannelName(tt.prefix, tt.template, data); got != tt.want {
+			t.Errorf("renderChannelName(%q, %q) = %q, want %q", tt.prefix, tt.template, got, tt.want)
+		}
+	}
+
+	// Long descriptions are cut short, keeping the sequence.
+	data.Description = strings.Repeat("database replication lag ", 10)
+	name := renderChannelName("incident-", "{slug(description)}-{seq}", data)
+	if len(name) > maxChannelNameLength || !strings.HasSuffix(name, "-42") || !strings.HasPrefix(name, "incident-database-replication") {
+		t.Errorf("long name = %q (%d characters)", name, len(name))
+	}
+
+	for template, want := range map[string]string{
+		"{date}-{sev}":  "must contain {seq} or {id}",
+		"{date}-{nope}": "unknown token {nope}",
+	} {
+		if err := validateChannelTemplate(template); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateChannelTemplate(%q) = %v, want %q", template, err, want)
+		}
+	}
+}
+
+func TestChannelNameSequence(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.config.ChannelPrefix = "incident-"
+	app.config.ChannelTemplate = "{sev}-{seq}"
+
+	// A channel left over from before takes the first number.
+	if _, err := app.slack.newClient().CreateConversation(slack.CreateConversationParams{ChannelName: "incident-sev-2-1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		severity Severity
+		want     string
+	}{
+		{SeveritySev2, "incident-sev-2-2"},
+		{SeveritySev1, "incident-sev-1-3"},
+	} {
+		incident, err := app.service.DeclareIncident(ctx, IncidentDeclaration{Description: "Queue backlog", Severity: tt.severity, Status: StatusInvestigating})
+		if err != nil {
+			t.Fatalf("DeclareIncident: %v", err)
+		}
+		if incident.ChannelName != tt.want {
+			t.Errorf("channel name = %q, want %q", incident.ChannelName, tt.want)
+		}
+	}
+
+	// Without {seq} a taken name can't be retried.
+	app.config.ChannelTemplate = "{id}"
+	if _, err := app.slack.newClient().CreateConversation(slack.CreateConversationParams{ChannelName: "incident-inc-3"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := app.service.DeclareIncident(ctx, IncidentDeclaration{Description: "Queue backlog", Severity: SeveritySev2, Status: StatusInvestigating})
+	if !errors.Is(err, ErrChannelNamesExhausted) {
+		t.Fatalf("DeclareIncident with a taken name: %v", err)
+	}
+	if _, err := app.store.GetIncident(ctx, "INC-3"); !errors.Is(err, ErrIncidentNotFound) {
+		t.Errorf("incident without a channel was kept: %v", err)
+	}
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Deploy failed", "severity": "sev2"}`, nil); code != http.StatusCreated {
+		t.Errorf("create after a removed incident: status %d", code)
+	}
+}
+
+func TestDeclareArchivesChannelOnFailure(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.slack.Fail("conversations.invite", "user_is_restricted")
+
+	_, err := app.service.DeclareIncident(ctx, IncidentDeclaration{Description: "Queue backlog", Severity: SeveritySev2, Status: StatusInvestigating, Members: []string{"U3"}})
+	if err == nil {
+		t.Fatal("DeclareIncident succeeded despite the failed invite")
+	}
+	created := app.slack.Calls("conversations.create")
+	if len(created) != 1 {
+		t.Fatalf("conversations.create calls = %d, want 1", len(created))
+	}
+	archived := app.slack.Calls("conversations.archive")
+	if len(archived) != 1 || !app.slack.Channel(archived[0].Form.Get("channel")).Archived {
+		t.Errorf("the abandoned channel wasn't archived: %v", archived)
+	}
+	if incidents, _ := app.store.ListIncidents(ctx, IncidentFilter{}); len(incidents) != 0 {
+		t.Errorf("incidents = %+v, want none", incidents)
+	}
+}
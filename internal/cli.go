@@ -0,0 +1,519 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const cliUsage = `Usage: hal [-config file] incident [-server url] [-token token] [-db path] <command> [arguments]
+
+Commands:
+  list [-status open|all|resolved|<status>] [-severity sev] [-limit n]
+  get <id>
+  timeline <id> [message] [-user slack-user-id]
+  resolve <id> [message] [-user slack-user-id]
+  export <id> [-format md|json]
+
+list, get and timeline print a table; add -json for JSON.
+
+With -server (or $HAL_URL) HAL's REST API is used, authenticated with -token
+(or $HAL_API_TOKEN). Otherwise the incident database named by the
+configuration, or -db, is read directly; adding timeline entries and
+resolving incidents need the server, since Slack has to be updated.
+`
+
+// errUsage marks invalid command lines; RunCLI prints the usage for them.
+var errUsage = errors.New("invalid usage")
+
+// cliBackend is where the CLI gets incidents from: HAL's REST API or the
+// incident store.
+type cliBackend interface {
+	ListIncidents(ctx context.Context, status, severity string, limit int) ([]*Incident, error)
+	GetIncident(ctx context.Context, id string) (*Incident, error)
+	ListTimeline(ctx context.Context, id string) ([]TimelineItem, error)
+	AddTimelineItem(ctx context.Context, id, userID, message string) error
+	ListActionItems(ctx context.Context, id string) ([]ActionItem, error)
+	ResolveIncident(ctx context.Context, id, userID, message string) (*Incident, error)
+}
+
+// IncidentExport is everything recorded about an incident.
+type IncidentExport struct {
+	Incident    *Incident      `json:"incident"`
+	Timeline    []TimelineItem `json:"timeline"`
+	ActionItems []ActionItem   `json:"action_items"`
+}
+
+// RunCLI runs `hal incident ...`, with args starting at "incident", and
+// returns the process exit code.
+func RunCLI(ctx context.Context, configPath string, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("hal incident", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+	server := flags.String("server", os.Getenv("HAL_URL"), "HAL server URL")
+	token := flags.String("token", os.Getenv("HAL_API_TOKEN"), "REST API token")
+	dbPath := flags.String("db", "", "incident database to read instead of the server")
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	var backend cliBackend
+	var taxonomy *Taxonomy
+	if *server != "" && *dbPath == "" {
+		backend = newAPIBackend(*server, *token, &http.Client{Timeout: 30 * time.Second})
+		// Labels come from the local configuration if there is one; without
+		// it severities and statuses are shown by ID.
+		if config, err := LoadConfig(configPath); err == nil {
+			taxonomy = config.Taxonomy
+		}
+	} else {
+		config, err := LoadConfig(configPath)
+		if err != nil && *dbPath == "" {
+			fmt.Fprintf(stderr, "hal: %v\n", err)
+			return 1
+		}
+		taxonomy = DefaultTaxonomy()
+		if err == nil {
+			taxonomy = config.Taxonomy
+			if *dbPath == "" {
+				*dbPath = config.DatabasePath
+			}
+		}
+		store, err := NewSQLStore(*dbPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "hal: %v\n", err)
+			return 1
+		}
+		defer store.Close()
+		reader := "hal incident"
+		if current, err := user.Current(); err == nil {
+			reader += " as " + current.Username
+		}
+		backend = &storeBackend{store: store, taxonomy: taxonomy, reader: reader}
+	}
+
+	err := runIncidentCommand(ctx, backend, taxonomy, flags.Args(), stdout)
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "hal: %v\n\n%s", err, cliUsage)
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "hal: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runIncidentCommand runs one CLI command. taxonomy labels severities and
+// statuses in the human-readable output and may be nil.
+func runIncidentCommand(ctx context.Context, backend cliBackend, taxonomy *Taxonomy, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command", errUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	asJSON := flags.Bool("json", false, "print JSON")
+	var status, severity, user, format string
+	var limit int
+	switch command {
+	case "list":
+		flags.StringVar(&status, "status", "open", "")
+		flags.StringVar(&severity, "severity", "", "")
+		flags.IntVar(&limit, "limit", 0, "")
+	case "timeline", "resolve":
+		flags.StringVar(&user, "user", "", "")
+	case "export":
+		flags.StringVar(&format, "format", "md", "")
+	case "get":
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if command == "list" {
+		if len(positional) > 0 {
+			return fmt.Errorf("%w: list takes no arguments", errUsage)
+		}
+		incidents, err := backend.ListIncidents(ctx, status, severity, limit)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stdout, incidents)
+		}
+		return writeIncidentTable(stdout, taxonomy, incidents, time.Now())
+	}
+
+	if len(positional) == 0 {
+		return fmt.Errorf("%w: %s needs an incident ID", errUsage, command)
+	}
+	id, message := positional[0], strings.TrimSpace(strings.Join(positional[1:], " "))
+	if message != "" && command != "timeline" && command != "resolve" {
+		return fmt.Errorf("%w: too many arguments for %s", errUsage, command)
+	}
+
+	switch command {
+	case "get":
+		incident, err := backend.GetIncident(ctx, id)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stdout, incident)
+		}
+		return writeIncident(stdout, taxonomy, incident)
+
+	case "timeline":
+		if message != "" {
+			if err := backend.AddTimelineItem(ctx, id, user, message); err != nil {
+				return err
+			}
+		}
+		items, err := backend.ListTimeline(ctx, id)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stdout, items)
+		}
+		return writeTimelineTable(stdout, items)
+
+	case "resolve":
+		incident, err := backend.ResolveIncident(ctx, id, user, message)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stdout, incident)
+		}
+		_, err = fmt.Fprintf(stdout, "Resolved %s: %s\n", incident.ID, incident.Description)
+		return err
+
+	default: // export
+		if format != "md" && format != "json" {
+			return fmt.Errorf("%w: unknown export format %q", errUsage, format)
+		}
+		export, err := exportIncident(ctx, backend, id)
+		if err != nil {
+			return err
+		}
+		if format == "json" {
+			return writeJSON(stdout, export)
+		}
+		_, err = io.WriteString(stdout, IncidentMarkdown(export, taxonomy))
+		return err
+	}
+}
+
+// parseInterspersed parses flags that may come before, between or after the
+// positional arguments, so `export INC-1 -format json` works.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func exportIncident(ctx context.Context, backend cliBackend, id string) (*IncidentExport, error) {
+	incident, err := backend.GetIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	timeline, err := backend.ListTimeline(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := backend.ListActionItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &IncidentExport{Incident: incident, Timeline: timeline, ActionItems: items}, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeIncidentTable(w io.Writer, taxonomy *Taxonomy, incidents []*Incident, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSEVERITY\tSTATUS\tAGE\tCHANNEL\tDESCRIPTION")
+	for _, incident := range incidents {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t#%s\t%s\n", incident.ID, taxonomy.SeverityLabel(incident.Severity), taxonomy.StatusLabel(incident.Status),
+			formatAge(now.Sub(incident.CreatedAt)), incident.ChannelName, incident.Description)
+	}
+	return tw.Flush()
+}
+
+func writeIncident(w io.Writer, taxonomy *Taxonomy, incident *Incident) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n", incident.ID)
+	fmt.Fprintf(tw, "Description\t%s\n", incident.Description)
+	fmt.Fprintf(tw, "Severity\t%s\n", taxonomy.SeverityLabel(incident.Severity))
+	fmt.Fprintf(tw, "Status\t%s\n", taxonomy.StatusLabel(incident.Status))
+	fmt.Fprintf(tw, "Commander\t%s\n", incident.CommanderID)
+	fmt.Fprintf(tw, "Comms rep\t%s\n", incident.CommsRepID)
+	roleIDs := make([]string, 0, len(incident.Roles))
+	for roleID := range incident.Roles {
+		roleIDs = append(roleIDs, roleID)
+	}
+	slices.Sort(roleIDs)
+	for _, roleID := range roleIDs {
+		fmt.Fprintf(tw, "Role %s\t%s\n", roleID, incident.Roles[roleID])
+	}
+	fmt.Fprintf(tw, "Channel\t#%s\n", incident.ChannelName)
+	fmt.Fprintf(tw, "Created\t%s\n", incident.CreatedAt.UTC().Format(time.RFC3339))
+	if incident.ResolvedAt != nil {
+		fmt.Fprintf(tw, "Resolved\t%s (after %s)\n", incident.ResolvedAt.UTC().Format(time.RFC3339), formatAge(incident.ResolvedAt.Sub(incident.CreatedAt)))
+	}
+	return tw.Flush()
+}
+
+func writeTimelineTable(w io.Writer, items []TimelineItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tUSER\tMESSAGE")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", item.Timestamp.UTC().Format(timelineTimeFormat), item.User, item.Message)
+	}
+	return tw.Flush()
+}
+
+// IncidentMarkdown renders an export as a Markdown document, labelling
+// severities and statuses from taxonomy, which may be nil.
+func IncidentMarkdown(export *IncidentExport, taxonomy *Taxonomy) string {
+	incident := export.Incident
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s: %s\n\n", incident.ID, incident.Description)
+	fmt.Fprintf(&b, "- **Severity:** %s\n", taxonomy.SeverityLabel(incident.Severity))
+	fmt.Fprintf(&b, "- **Status:** %s\n", taxonomy.StatusLabel(incident.Status))
+	if incident.CommanderID != "" {
+		fmt.Fprintf(&b, "- **Commander:** %s\n", incident.CommanderID)
+	}
+	if incident.CommsRepID != "" {
+		fmt.Fprintf(&b, "- **Comms rep:** %s\n", incident.CommsRepID)
+	}
+	fmt.Fprintf(&b, "- **Channel:** #%s\n", incident.ChannelName)
+	fmt.Fprintf(&b, "- **Declared:** %s\n", incident.CreatedAt.UTC().Format(timelineTimeFormat))
+	if incident.ResolvedAt != nil {
+		fmt.Fprintf(&b, "- **Resolved:** %s (after %s)\n", incident.ResolvedAt.UTC().Format(timelineTimeFormat), formatAge(incident.ResolvedAt.Sub(incident.CreatedAt)))
+	}
+
+	b.WriteString("\n## Timeline\n\n")
+	for _, item := range export.Timeline {
+		fmt.Fprintf(&b, "- %s - %s", item.Timestamp.UTC().Format(timelineTimeFormat), item.Message)
+		if item.User != "" {
+			fmt.Fprintf(&b, " (%s)", item.User)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n## Action Items\n\n")
+	if len(export.ActionItems) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, item := range export.ActionItems {
+		check := " "
+		if item.Completed {
+			check = "x"
+		}
+		fmt.Fprintf(&b, "- [%s] %d. %s", check, item.Number, item.Description)
+		if item.Assignee != "" {
+			fmt.Fprintf(&b, " (assigned to %s)", item.Assignee)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// storeBackend reads the incident store directly. Reads of confidential
+// incidents are recorded in their audit log with reader as the detail.
+type storeBackend struct {
+	store    IncidentStore
+	taxonomy *Taxonomy
+	reader   string
+}
+
+var errNeedsServer = errors.New("this command needs the HAL server (-server), since Slack has to be updated")
+
+func (b *storeBackend) ListIncidents(ctx context.Context, status, severity string, limit int) ([]*Incident, error) {
+	filter, err := queryFilter(b.taxonomy, status, severity, limit)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := b.store.ListIncidents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	auditReads(ctx, b.store, incidents, "", b.reader)
+	return incidents, nil
+}
+
+func (b *storeBackend) GetIncident(ctx context.Context, id string) (*Incident, error) {
+	incident, err := b.store.GetIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	auditReads(ctx, b.store, []*Incident{incident}, "", b.reader)
+	return incident, nil
+}
+
+func (b *storeBackend) ListTimeline(ctx context.Context, id string) ([]TimelineItem, error) {
+	if _, err := b.GetIncident(ctx, id); err != nil {
+		return nil, err
+	}
+	return b.store.ListTimelineItems(ctx, id)
+}
+
+func (b *storeBackend) AddTimelineItem(ctx context.Context, id, userID, message string) error {
+	return errNeedsServer
+}
+
+func (b *storeBackend) ListActionItems(ctx context.Context, id string) ([]ActionItem, error) {
+	if _, err := b.GetIncident(ctx, id); err != nil {
+		return nil, err
+	}
+	return b.store.ListActionItems(ctx, id)
+}
+
+func (b *storeBackend) ResolveIncident(ctx context.Context, id, userID, message string) (*Incident, error) {
+	return nil, errNeedsServer
+}
+
+// apiBackend talks to HAL's REST API.
+type apiBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newAPIBackend(server, token string, client *http.Client) *apiBackend {
+	return &apiBackend{baseURL: strings.TrimSuffix(server, "/") + "/api/v1", token: token, client: client}
+}
+
+func (b *apiBackend) ListIncidents(ctx context.Context, status, severity string, limit int) ([]*Incident, error) {
+	query := url.Values{}
+	query.Set("status", status)
+	if severity != "" {
+		query.Set("severity", severity)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		Incidents []*Incident `json:"incidents"`
+	}
+	err := b.do(ctx, http.MethodGet, "/incidents?"+query.Encode(), nil, &resp)
+	return resp.Incidents, err
+}
+
+func (b *apiBackend) GetIncident(ctx context.Context, id string) (*Incident, error) {
+	var incident Incident
+	if err := b.do(ctx, http.MethodGet, "/incidents/"+url.PathEscape(id), nil, &incident); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func (b *apiBackend) ListTimeline(ctx context.Context, id string) ([]TimelineItem, error) {
+	var resp struct {
+		Timeline []TimelineItem `json:"timeline"`
+	}
+	err := b.do(ctx, http.MethodGet, "/incidents/"+url.PathEscape(id)+"/timeline", nil, &resp)
+	return resp.Timeline, err
+}
+
+func (b *apiBackend) AddTimelineItem(ctx context.Context, id, userID, message string) error {
+	return b.do(ctx, http.MethodPost, "/incidents/"+url.PathEscape(id)+"/timeline", timelineRequest{Message: message, UserID: userID}, nil)
+}
+
+func (b *apiBackend) ListActionItems(ctx context.Context, id string) ([]ActionItem, error) {
+	var resp struct {
+		ActionItems []ActionItem `json:"action_items"`
+	}
+	err := b.do(ctx, http.MethodGet, "/incidents/"+url.PathEscape(id)+"/action-items", nil, &resp)
+	return resp.ActionItems, err
+}
+
+func (b *apiBackend) ResolveIncident(ctx context.Context, id, userID, message string) (*Incident, error) {
+	var incident Incident
+	err := b.do(ctx, http.MethodPost, "/incidents/"+url.PathEscape(id)+"/resolve", resolveRequest{Message: message, UserID: userID}, &incident)
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// do sends a request to the API and decodes the response into out, if given.
+// Error responses are returned as errors carrying the API's message.
+func (b *apiBackend) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach HAL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return fmt.Errorf("HAL returned %d: %s", resp.StatusCode, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
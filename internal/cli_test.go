@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCLI(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.router)
+	defer server.Close()
+
+	api := newAPIBackend(server.URL, testAPIToken, http.DefaultClient)
+	store := &storeBackend{store: app.store, taxonomy: app.config.Taxonomy}
+	run := func(backend cliBackend, args ...string) (string, error) {
+		t.Helper()
+		var out strings.Builder
+		err := runIncidentCommand(context.Background(), backend, app.config.Taxonomy, args, &out)
+		return out.String(), err
+	}
+
+	app.interaction(t, createIncidentSubmission("V1200", "Search is slow", "SEV-2"))
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	id := app.incident().ID
+
+	for name, backend := range map[string]cliBackend{"api": api, "store": store} {
+		out, err := run(backend, "list", "-severity", "sev2")
+		if err != nil || !strings.Contains(out, id) || !strings.Contains(out, "Search is slow") {
+			t.Errorf("%s: list = %q, %v", name, out, err)
+		}
+		out, err = run(backend, "get", id, "-json")
+		var incident Incident
+		if err != nil || json.Unmarshal([]byte(out), &incident) != nil || incident.Severity != SeveritySev2 {
+			t.Errorf("%s: get -json = %q, %v", name, out, err)
+		}
+		if _, err := run(backend, "get", "INC-999"); err == nil {
+			t.Errorf("%s: get of a missing incident succeeded", name)
+		}
+	}
+
+	if _, err := run(store, "timeline", id, "rolled", "back"); !errors.Is(err, errNeedsServer) {
+		t.Errorf("store timeline add: %v", err)
+	}
+	out, err := run(api, "timeline", id, "rolled", "back", "-user", "U0ONCALL")
+	if err != nil || !strings.Contains(out, "rolled back") || !strings.Contains(out, "U0ONCALL") {
+		t.Errorf("timeline add = %q, %v", out, err)
+	}
+	if !strings.Contains(app.slack.PinnedText(app.incident().ChannelID), "rolled back") {
+		t.Error("pinned timeline not updated")
+	}
+
+	if out, err := run(api, "resolve", id, "fixed", "the", "index"); err != nil || !strings.Contains(out, "Resolved "+id) {
+		t.Errorf("resolve = %q, %v", out, err)
+	}
+
+	out, err = run(store, "export", id, "-format", "md")
+	for _, want := range []string{"# " + id + ": Search is slow", "- **Status:** Resolved", "rolled back (U0ONCALL)", "Incident resolved. Resolution: fixed the index", "- [ ] 1. Create incident postmortem"} {
+		if err != nil || !strings.Contains(out, want) {
+			t.Errorf("export md missing %q: %q, %v", want, out, err)
+		}
+	}
+	out, err = run(api, "export", id, "-format", "json")
+	var export IncidentExport
+	if err != nil || json.Unmarshal([]byte(out), &export) != nil || len(export.ActionItems) != 1 || len(export.Timeline) != 3 {
+		t.Errorf("export json = %q, %v", out, err)
+	}
+
+	if _, err := run(api, "frobnicate"); !errors.Is(err, errUsage) {
+		t.Errorf("unknown command: %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseCommand splits `/incident` text into its lower-cased command and the
+// remaining arguments.
+func parseCommand(text string) (command, args string) {
+	command, args, _ = strings.Cut(strings.TrimSpace(text), " ")
+	return strings.ToLower(command), strings.TrimSpace(args)
+}
+
+// commandOpensModal reports whether a command opens a modal. Those must run
+// before the handler returns because Slack trigger IDs expire after three
+// seconds; everything else is queued.
+func commandOpensModal(text string) bool {
+	switch command, _ := parseCommand(text); command {
+	case "create", "c", "update", "u":
+		return true
+	}
+	return false
+}
+
+// HandleCommand runs an `/incident` slash command once per trigger ID. Usage
+// hints and results are sent to the user directly; the returned error is for
+// failures the caller should report.
+func (s *IncidentService) HandleCommand(ctx context.Context, req SlackCommandRequest) error {
+	key := ""
+	if req.TriggerId != "" {
+		key = "trigger:" + req.TriggerId
+	}
+	_, shared, err := s.idempotency.Do(ctx, key, func() (any, error) {
+		return nil, s.handleCommand(ctx, req)
+	})
+	if shared {
+		slog.InfoContext(ctx, "Skipped duplicate command", "text", req.Text, "error", err)
+		return nil
+	}
+	return err
+}
+
+func (s *IncidentService) handleCommand(ctx context.Context, req SlackCommandRequest) error {
+	target := ResponseTarget{ResponseURL: req.ResponseUrl, ChannelID: req.ChannelId, UserID: req.UserId}
+	command, args := parseCommand(req.Text)
+
+	switch command {
+	case "create", "c":
+		err := s.CreateIncident(ctx, req.TriggerId)
+		if err != nil {
+			return fmt.Errorf("could not open dialog: %w", err)
+		}
+
+	case "update", "u":
+		err := s.UpdateIncident(ctx, req.TriggerId, req.ChannelId)
+		if err != nil {
+			return fmt.Errorf("could not open update dialog: %w", err)
+		}
+
+	case "timeline", "t":
+		if args == "" {
+			return s.slackService.RespondText(ctx, target, "Usage: /incident timeline <message> (or /incident t <message>)")
+		}
+		err := s.AddTimelineItem(ctx, req.ChannelId, req.UserId, args)
+		if err != nil {
+			return fmt.Errorf("could not add timeline item: %w", err)
+		}
+
+	case "action-item", "ai":
+		return s.handleActionItemCommand(ctx, req, target, args)
+
+	case "role":
+		return s.handleRoleCommand(ctx, req, target, args)
+
+	case "handoff":
+		return s.handleHandoffCommand(ctx, req, target, args)
+
+	case "resolve", "r":
+		// Ephemeral confirmation is handled within ResolveIncident
+		err := s.ResolveIncident(ctx, req.ChannelId, req.UserId, args)
+		if err != nil {
+			return fmt.Errorf("failed to resolve incident: %w", err)
+		}
+
+	case "reopen":
+		return s.handleReopenCommand(ctx, req, target, args)
+
+	case "stats":
+		period, err := parseStatsPeriod(args)
+		if err != nil {
+			return s.slackService.RespondText(ctx, target, "Usage: /incident stats [7d|12w|all] (defaults to the last 30 days)")
+		}
+		stats, err := s.Stats(ctx, period, time.Now())
+		if err != nil {
+			return fmt.Errorf("could not compute stats: %w", err)
+		}
+		return s.slackService.Respond(ctx, target, s.StatsMessage(stats)...)
+
+	case "postmortem", "pm":
+		err := s.PostPostmortem(ctx, req.ChannelId, req.UserId)
+		if err != nil {
+			return fmt.Errorf("could not generate postmortem: %w", err)
+		}
+
+	case "list", "ls":
+		filter, err := parseListArgs(s.config().Taxonomy, args)
+		if err != nil {
+			severities := make([]string, 0, len(s.config().Taxonomy.Severities))
+			for _, level := range s.config().Taxonomy.Severities {
+				severities = append(severities, string(level.ID))
+			}
+			return s.slackService.RespondText(ctx, target, fmt.Sprintf("Usage: /incident list [all|open|resolved] [%s]", strings.Join(severities, "|")))
+		}
+		filter.Limit = maxListedIncidents + 1
+		incidents, err := s.listVisibleIncidents(ctx, filter, req.UserId, AuditList)
+		if err != nil {
+			return fmt.Errorf("could not list incidents: %w", err)
+		}
+		return s.slackService.Respond(ctx, target, s.IncidentListMessage(incidents, filter, time.Now())...)
+
+	case "help", "h":
+		// The response URL works even in channels HAL isn't a member of.
+		return s.slackService.Respond(ctx, target, s.HelpMessage().BlockSet...)
+
+	case "": // Handles the case where only /incident is typed
+		return s.slackService.RespondText(ctx, target, "Please provide a command. Use `/incident help` (or `/incident h`) for details.")
+
+	default:
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("Command `%s` not found. Use `/incident help` (or `/incident h`) for details.", command))
+	}
+
+	return nil
+}
+
+func (s *IncidentService) handleActionItemCommand(ctx context.Context, req SlackCommandRequest, target ResponseTarget, args string) error {
+	if args == "" {
+		return s.slackService.RespondText(ctx, target, "Usage: /incident action-item [@user] <description> (or /incident ai [@user] <description>)")
+	}
+
+	subcommand, subArgs := parseCommand(args)
+	switch subcommand {
+	case "list", "ls":
+		items, err := s.ListActionItems(ctx, req.ChannelId)
+		if err != nil {
+			return fmt.Errorf("could not list action items: %w", err)
+		}
+		return s.slackService.Respond(ctx, target, s.ActionItemsListMessage(items)...)
+
+	case "done", "undone":
+		number, err := strconv.Atoi(strings.TrimPrefix(subArgs, "#"))
+		if err != nil {
+			return s.slackService.RespondText(ctx, target, fmt.Sprintf("Usage: /incident ai %s <number>. Use `/incident ai list` to see item numbers.", subcommand))
+		}
+		_, err = s.SetActionItemCompleted(ctx, req.ChannelId, req.UserId, number, subcommand == "done")
+		if errors.Is(err, ErrActionItemNotFound) {
+			return s.slackService.RespondText(ctx, target, fmt.Sprintf("Action item %d not found. Use `/incident ai list` to see item numbers.", number))
+		}
+		if err != nil {
+			return fmt.Errorf("could not update action item: %w", err)
+		}
+
+	default:
+		// A leading mention assigns the item.
+		var assignee string
+		if mention, description, ok := strings.Cut(args, " "); ok {
+			if userID, ok := parseUserMention(mention); ok {
+				assignee, args = userID, strings.TrimSpace(description)
+			}
+		}
+		err := s.AddActionItem(ctx, req.ChannelId, req.UserId, assignee, args)
+		if err != nil {
+			return fmt.Errorf("could not add action item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *IncidentService) handleReopenCommand(ctx context.Context, req SlackCommandRequest, target ResponseTarget, args string) error {
+	// Archived channels can't run commands, so the incident can be named.
+	incidentID, reason := parseCommand(args)
+	if isIncidentID(incidentID) {
+		incidentID = strings.ToUpper(incidentID)
+		// Naming a confidential incident from outside it mustn't reveal that
+		// it exists.
+		incident, err := s.GetIncident(ctx, incidentID)
+		if err == nil && incident.Confidential {
+			allowed := canView(incident, req.UserId)
+			s.audit(ctx, incident, AuditReopen, req.UserId, allowed, "")
+			if !allowed {
+				err = ErrIncidentNotFound
+			}
+		}
+		if errors.Is(err, ErrIncidentNotFound) {
+			return s.slackService.RespondText(ctx, target, fmt.Sprintf("Incident %s not found.", incidentID))
+		}
+		if err != nil {
+			return fmt.Errorf("could not load incident: %w", err)
+		}
+	} else {
+		incident, err := s.incidentForChannel(ctx, req.ChannelId)
+		if err != nil {
+			return err
+		}
+		incidentID, reason = incident.ID, args
+	}
+
+	incident, err := s.ReopenIncident(ctx, incidentID, req.UserId, reason)
+	switch {
+	case errors.Is(err, ErrIncidentNotResolved):
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("%s isn't resolved, so there's nothing to reopen.", incidentID))
+	case errors.Is(err, ErrIncidentNotFound):
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("Incident %s not found.", incidentID))
+	case err != nil:
+		return fmt.Errorf("could not reopen incident: %w", err)
+	}
+
+	return s.slackService.RespondText(ctx, target, fmt.Sprintf(":arrows_counterclockwise: Reopened %s in <#%s> as %s.", incident.ID, incident.ChannelID, incident.Status))
+}
+
+func (s *IncidentService) handleRoleCommand(ctx context.Context, req SlackCommandRequest, target ResponseTarget, args string) error {
+	taxonomy := s.config().Taxonomy
+	roleIDs := make([]string, 0, len(taxonomy.Roles))
+	for _, role := range taxonomy.Roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	usage := fmt.Sprintf("Usage: /incident role assign <role> @user, /incident role unassign <role> or /incident role list. Roles: %s", strings.Join(roleIDs, ", "))
+
+	subcommand, subArgs := parseCommand(args)
+	var roleName, userID string
+	switch subcommand {
+	case "", "list", "ls":
+		incident, err := s.incidentForChannel(ctx, req.ChannelId)
+		if err != nil {
+			return err
+		}
+		return s.slackService.Respond(ctx, target, s.RoleListMessage(incident)...)
+
+	case "assign":
+		// Role labels may have spaces, so the user is the last argument.
+		fields := strings.Fields(subArgs)
+		if len(fields) < 2 {
+			return s.slackService.RespondText(ctx, target, usage)
+		}
+		var ok bool
+		userID, ok = parseUserMention(fields[len(fields)-1])
+		if !ok {
+			return s.slackService.RespondText(ctx, target, usage)
+		}
+		roleName = strings.Join(fields[:len(fields)-1], " ")
+
+	case "unassign":
+		if subArgs == "" {
+			return s.slackService.RespondText(ctx, target, usage)
+		}
+		roleName = subArgs
+
+	default:
+		return s.slackService.RespondText(ctx, target, usage)
+	}
+
+	role, ok := taxonomy.ParseRole(roleName)
+	if !ok {
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("Unknown role `%s`. Roles: %s", roleName, strings.Join(roleIDs, ", ")))
+	}
+	_, err := s.AssignRole(ctx, req.ChannelId, req.UserId, role.ID, userID)
+	switch {
+	case errors.Is(err, ErrRoleUnchanged) && userID == "":
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("Nobody is the %s.", role.Label))
+	case errors.Is(err, ErrRoleUnchanged):
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("<@%s> is already the %s.", userID, role.Label))
+	case err != nil:
+		return fmt.Errorf("could not assign role: %w", err)
+	}
+	return nil
+}
+
+func (s *IncidentService) handleHandoffCommand(ctx context.Context, req SlackCommandRequest, target ResponseTarget, args string) error {
+	userID, ok := parseUserMention(args)
+	if !ok {
+		return s.slackService.RespondText(ctx, target, "Usage: /incident handoff @user")
+	}
+	_, err := s.RequestHandoff(ctx, req.ChannelId, req.UserId, userID)
+	switch {
+	case errors.Is(err, ErrRoleUnchanged):
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("<@%s> is already the commander.", userID))
+	case errors.Is(err, ErrIncidentResolved):
+		return s.slackService.RespondText(ctx, target, "This incident is resolved, so there's no command to hand off. Use `/incident reopen` first if it needs one again.")
+	case errors.Is(err, ErrIncidentConfidential):
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("<@%s> isn't part of this confidential incident. Add them to the channel or give them a role first.", userID))
+	case err != nil:
+		return fmt.Errorf("could not request handoff: %w", err)
+	}
+	return s.slackService.RespondText(ctx, target, fmt.Sprintf("I've sent <@%s> a briefing. They'll take over as commander once they accept.", userID))
+}
+
+// parseUserMention reads a Slack user mention such as <@U123> or
+// <@U123|alice>.
+func parseUserMention(s string) (string, bool) {
+	mention, ok := strings.CutPrefix(s, "<@")
+	if !ok || !strings.HasSuffix(mention, ">") {
+		return "", false
+	}
+	userID, _, _ := strings.Cut(strings.TrimSuffix(mention, ">"), "|")
+	return userID, userID != ""
+}
+
+// isIncidentID reports whether s looks like an incident ID such as INC-12.
+func isIncidentID(s string) bool {
+	number, ok := strings.CutPrefix(strings.ToUpper(s), "INC-")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(number)
+	return err == nil
+}
+
+// ReportFailure tells the user that work done on their behalf failed.
+func (s *IncidentService) ReportFailure(ctx context.Context, target ResponseTarget, err error) {
+	text := fmt.Sprintf(":warning: Sorry, something went wrong: %s", err)
+	if errors.Is(err, ErrIncidentNotFound) {
+		text = ":warning: This doesn't look like an incident channel. Use `/incident create` to declare an incident."
+	}
+
+	if respondErr := s.slackService.RespondText(ctx, target, text); respondErr != nil {
+		slog.ErrorContext(ctx, "Failed to report failure to user", "userID", target.UserID, "error", respondErr, "original_error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// audit records an access-control decision about a confidential incident.
+// The caller has already acted on the decision, and refusing a user because
+// the log is unavailable wouldn't help them, so failures are only logged.
+func (s *IncidentService) audit(ctx context.Context, incident *Incident, action AuditAction, userID string, allowed bool, detail string) {
+	recordAudit(ctx, s.store, &AuditEntry{IncidentID: incident.ID, Action: action, User: userID, Allowed: allowed, Detail: detail})
+}
+
+// recordAudit is audit for the CLI and the webhook dispatcher, which have the
+// store but no IncidentService.
+func recordAudit(ctx context.Context, store IncidentStore, entry *AuditEntry) {
+	if err := store.AddAuditEntry(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "Failed to record audit entry", "incidentID", entry.IncidentID, "action", entry.Action, "error", err)
+	}
+}
+
+// auditReads records reads of the confidential incidents among incidents
+// through the API or the CLI. Those hold the API token or the database, so
+// the read is always allowed; userID is whoever the caller says they are, if
+// anyone, and detail says how the incidents were read.
+func auditReads(ctx context.Context, store IncidentStore, incidents []*Incident, userID, detail string) {
+	for _, incident := range incidents {
+		if incident.Confidential {
+			recordAudit(ctx, store, &AuditEntry{IncidentID: incident.ID, Action: AuditRead, User: userID, Allowed: true, Detail: detail})
+		}
+	}
+}
+
+// canView reports whether a user may see an incident outside its channel.
+// Anyone may see incidents that aren't confidential.
+func canView(incident *Incident, userID string) bool {
+	return !incident.Confidential || isInvolved(incident, userID)
+}
+
+// visibleIncidents drops the confidential incidents the user isn't involved
+// in, auditing the decision for each confidential incident.
+func (s *IncidentService) visibleIncidents(ctx context.Context, incidents []*Incident, userID string, action AuditAction) []*Incident {
+	visible := incidents[:0:0]
+	for _, incident := range incidents {
+		if s.auditedCanView(ctx, incident, userID, action) {
+			visible = append(visible, incident)
+		}
+	}
+	return visible
+}
+
+// listVisibleIncidents lists the incidents matching filter that the user may
+// see. With a limit it keeps reading until it has that many, so the hidden
+// confidential incidents don't shorten the list; only the incidents it looks
+// at are audited.
+func (s *IncidentService) listVisibleIncidents(ctx context.Context, filter IncidentFilter, userID string, action AuditAction) ([]*Incident, error) {
+	if filter.Limit <= 0 {
+		incidents, err := s.ListIncidents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return s.visibleIncidents(ctx, incidents, userID, action), nil
+	}
+
+	var visible []*Incident
+	for {
+		page, err := s.ListIncidents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, incident := range page {
+			if s.auditedCanView(ctx, incident, userID, action) {
+				visible = append(visible, incident)
+				if len(visible) == filter.Limit {
+					return visible, nil
+				}
+			}
+		}
+		if len(page) < filter.Limit {
+			return visible, nil
+		}
+		filter.Offset += len(page)
+	}
+}
+
+// auditedCanView is canView, auditing the decision if the incident is
+// confidential.
+func (s *IncidentService) auditedCanView(ctx context.Context, incident *Incident, userID string, action AuditAction) bool {
+	allowed := canView(incident, userID)
+	if incident.Confidential {
+		s.audit(ctx, incident, action, userID, allowed, "")
+	}
+	return allowed
+}
+
+// ListAuditLog returns the audit log of an incident, oldest first.
+func (s *IncidentService) ListAuditLog(ctx context.Context, incidentID string) ([]AuditEntry, error) {
+	entries, err := s.store.ListAuditEntries(ctx, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	return entries, nil
+}
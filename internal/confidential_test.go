@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestConfidentialIncident(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.config.BroadcastChannel = "C0BROADCAST"
+
+	var secret, public Incident
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Suspicious logins", "severity": "SEV-1", "user_id": "U7", "members": ["U8"], "confidential": true}`, &secret); code != http.StatusCreated {
+		t.Fatalf("create confidential: status %d", code)
+	}
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Slow search", "severity": "SEV-3", "user_id": "U7"}`, &public); code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+
+	if !secret.Confidential || !app.slack.Channel(secret.ChannelID).Private || app.slack.Channel(public.ChannelID).Private {
+		t.Errorf("confidential %v, private channels %v and %v", secret.Confidential, app.slack.Channel(secret.ChannelID).Private, app.slack.Channel(public.ChannelID).Private)
+	}
+	if announced := len(app.slack.Channel("C0BROADCAST").Messages); announced != 1 {
+		t.Errorf("announcements = %d, want only the public incident", announced)
+	}
+
+	// People outside the incident don't see it listed.
+	app.command(t, "C0GENERAL", "list")
+	eventually(t, "list response", func() bool { return len(app.slack.Calls("response_url")) == 1 })
+	list, _ := json.Marshal(app.slack.Calls("response_url")[0].JSON["blocks"])
+	if !strings.Contains(string(list), "Slow search") || strings.Contains(string(list), "Suspicious logins") {
+		t.Errorf("list shows %s", list)
+	}
+
+	// Nor can they join it.
+	var click slack.InteractionCallback
+	click.Type = slack.InteractionTypeBlockActions
+	click.TriggerID = "T-join"
+	click.User = slack.User{ID: "U1"}
+	click.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: joinIncidentActionID, Value: secret.ID}}
+	app.interaction(t, click)
+
+	var audit struct {
+		AuditLog []AuditEntry `json:"audit_log"`
+	}
+	eventually(t, "audit entries", func() bool {
+		app.api(t, http.MethodGet, "/incidents/"+secret.ID+"/audit", "", &audit)
+		return len(audit.AuditLog) == 4
+	})
+	for i, want := range []AuditEntry{
+		{Action: AuditPrivateChannel, User: "U7", Allowed: true, Detail: "#" + secret.ChannelName},
+		{Action: AuditBroadcastSkipped, User: "U7", Detail: "C0BROADCAST"},
+		{Action: AuditList, User: "U1"},
+		{Action: AuditJoin, User: "U1"},
+	} {
+		got := audit.AuditLog[i]
+		if got.Action != want.Action || got.User != want.User || got.Allowed != want.Allowed || got.Detail != want.Detail {
+			t.Errorf("audit entry %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if members := app.slack.Channel(secret.ChannelID).Members; strings.Contains(strings.Join(members, ","), "U1") {
+		t.Errorf("U1 joined the confidential channel: %v", members)
+	}
+
+	// Reads through the API and the CLI aren't checked, but they are
+	// recorded.
+	if code := app.api(t, http.MethodGet, "/incidents/"+secret.ID+"/timeline?user_id=U9", "", nil); code != http.StatusOK {
+		t.Errorf("timeline: status %d", code)
+	}
+	cli := &storeBackend{store: app.store, taxonomy: app.config.Taxonomy, reader: "hal incident as ops"}
+	if _, err := cli.ListIncidents(ctx, "open", "", 0); err != nil {
+		t.Errorf("CLI list: %v", err)
+	}
+	entries, _ := app.store.ListAuditEntries(ctx, secret.ID)
+	for i, want := range []AuditEntry{
+		{Action: AuditRead, User: "U9", Allowed: true, Detail: "GET /api/v1/incidents/" + secret.ID + "/timeline"},
+		{Action: AuditRead, Allowed: true, Detail: "hal incident as ops"},
+	} {
+		if got := entries[len(entries)-2+i]; got.Action != want.Action || got.User != want.User || got.Allowed != want.Allowed || got.Detail != want.Detail {
+			t.Errorf("read entry %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if entries, _ := app.store.ListAuditEntries(ctx, public.ID); len(entries) != 0 {
+		t.Errorf("public incident audit log = %+v", entries)
+	}
+
+	// The create modal has the toggle.
+	submission := createIncidentSubmission("V1500", "Payroll data exposed", "SEV-1")
+	submission.View.State.Values["confidential"] = map[string]slack.BlockAction{
+		"confidential": {SelectedOptions: []slack.OptionBlockObject{{Value: "confidential"}}},
+	}
+	app.interaction(t, submission)
+	eventually(t, "confidential incident from the modal", func() bool {
+		incidents, _ := app.store.ListIncidents(ctx, IncidentFilter{})
+		return len(incidents) == 3 && incidents[0].Confidential && incidents[0].ChannelID != ""
+	})
+}
+
+func TestListSkipsHiddenIncidents(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	// The newest incidents are confidential, so the first page is mostly
+	// hidden from U1.
+	for i := 0; i < maxListedIncidents+6; i++ {
+		incident := &Incident{Description: fmt.Sprintf("Incident %d", i), Severity: SeveritySev3, Status: StatusInvestigating, Confidential: i >= maxListedIncidents+1}
+		if err := app.store.CreateIncident(ctx, incident); err != nil {
+			t.Fatalf("CreateIncident: %v", err)
+		}
+	}
+
+	app.command(t, "C0GENERAL", "list")
+	eventually(t, "list response", func() bool { return len(app.slack.Calls("response_url")) == 1 })
+	list, _ := json.Marshal(app.slack.Calls("response_url")[0].JSON["blocks"])
+	if !strings.Contains(string(list), fmt.Sprintf("Showing the %d most recent", maxListedIncidents)) {
+		t.Errorf("list isn't marked as cut short: %s", list)
+	}
+	if strings.Contains(string(list), fmt.Sprintf("Incident %d", maxListedIncidents+1)) {
+		t.Errorf("list shows a confidential incident: %s", list)
+	}
+
+	// Each hidden incident is audited once.
+	incidents, _ := app.store.ListIncidents(ctx, IncidentFilter{})
+	for _, incident := range incidents {
+		if !incident.Confidential {
+			continue
+		}
+		entries, _ := app.store.ListAuditEntries(ctx, incident.ID)
+		if len(entries) != 1 || entries[0].Action != AuditList || entries[0].Allowed {
+			t.Errorf("%s audit log = %+v", incident.ID, entries)
+		}
+	}
+}
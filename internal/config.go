@@ -1,15 +1,29 @@
 package internal
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
-func LoadConfig() (*Config, error) {
+// LoadConfig reads the configuration file at path, if any, then applies
+// environment variable overrides and validates the result. All problems are
+// returned together.
+func LoadConfig(path string) (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
 		if os.Getenv("ENVIRONMENT") != "production" {
@@ -18,69 +32,308 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config := &Config{
-		SlackToken:         getEnv("SLACK_TOKEN", "", true),
-		SlackSigningSecret: getEnv("SLACK_SIGNING_SECRET", "", true),
-		ServerPort:         getEnvAsInt("SERVER_PORT", 50051, false),
-		ServerHost:         getEnv("SERVER_HOST", "0.0.0.0", false),
-		Environment:        getEnv("ENVIRONMENT", "development", false),
-		LogLevel:           getEnv("LOG_LEVEL", "info", false),
+		ServerPort:         50051,
+		ServerHost:         "0.0.0.0",
+		Environment:        "development",
+		LogLevel:           "info",
+		DatabasePath:       "hal.db",
+		WorkerCount:        4,
+		WorkQueueSize:      100,
+		JobTimeout:         2 * time.Minute,
+		ShutdownTimeout:    30 * time.Second,
+		IdempotencyTTL:     15 * time.Minute,
+		LifecycleInterval:  5 * time.Minute,
+		ChannelPrefix:      "incident-",
+		ChannelTemplate:    defaultChannelTemplate,
+		Taxonomy:           DefaultTaxonomy(),
+		WebhookMaxAttempts: 6,
+		WebhookRetryDelay:  5 * time.Second,
+		Alertmanager:       AlertmanagerConfig{SeverityLabel: "severity"},
 	}
 
+	if path != "" {
+		if err := readConfigFile(path, config); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &envOverrides{}
+	env.string("SLACK_TOKEN", &config.SlackToken)
+	env.string("SLACK_SIGNING_SECRET", &config.SlackSigningSecret)
+	env.string("SLACK_APP_TOKEN", &config.SlackAppToken)
+	env.bool("SLACK_SOCKET_MODE", &config.SocketMode)
+	env.int("SERVER_PORT", &config.ServerPort)
+	env.string("SERVER_HOST", &config.ServerHost)
+	env.string("ENVIRONMENT", &config.Environment)
+	env.string("LOG_LEVEL", &config.LogLevel)
+	env.string("DATABASE_PATH", &config.DatabasePath)
+	env.int("WORKER_COUNT", &config.WorkerCount)
+	env.int("WORK_QUEUE_SIZE", &config.WorkQueueSize)
+	env.duration("JOB_TIMEOUT", &config.JobTimeout)
+	env.duration("SHUTDOWN_TIMEOUT", &config.ShutdownTimeout)
+	env.duration("IDEMPOTENCY_TTL", &config.IdempotencyTTL)
+	env.duration("LIFECYCLE_INTERVAL", &config.LifecycleInterval)
+	env.string("CHANNEL_PREFIX", &config.ChannelPrefix)
+	env.string("CHANNEL_TEMPLATE", &config.ChannelTemplate)
+	env.int("WEBHOOK_MAX_ATTEMPTS", &config.WebhookMaxAttempts)
+	env.duration("WEBHOOK_RETRY_DELAY", &config.WebhookRetryDelay)
+	env.string("API_TOKEN", &config.APIToken)
+	env.string("POSTMORTEM_TEMPLATE", &config.PostmortemTemplate)
+	env.string("BROADCAST_CHANNEL", &config.BroadcastChannel)
+	env.string("ALERTMANAGER_TOKEN", &config.Alertmanager.Token)
+	if taxonomyPath, ok := os.LookupEnv("TAXONOMY_PATH"); ok && taxonomyPath != "" {
+		taxonomy, err := LoadTaxonomy(taxonomyPath)
+		if err != nil {
+			env.errs = append(env.errs, err)
+		} else {
+			config.Taxonomy = taxonomy
+		}
+	}
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, err
+	}
+
+	config.Taxonomy.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
 	return config, nil
 }
 
-func getEnv(key, defaultValue string, required bool) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		if required {
-			panic(fmt.Sprintf("Required environment variable %s is not set", key))
+// readConfigFile decodes a YAML or TOML file, chosen by extension, over
+// config. TOML is converted to YAML first so both formats share the yaml
+// struct tags and duration parsing.
+func readConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		var doc map[string]any
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
 		}
-		return defaultValue
+		if data, err = yaml.Marshal(doc); err != nil {
+			return fmt.Errorf("failed to convert config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
-	return value
+	return nil
 }
 
-func getEnvAsInt(key string, defaultValue int, required bool) int {
-	valueStr := getEnv(key, "", required)
-	if valueStr == "" {
-		return defaultValue
+var channelPrefixPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Validate checks required settings and value ranges.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.SlackToken == "" {
+		errs = append(errs, errors.New("slack_token is required"))
 	}
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		if required {
-			panic(fmt.Sprintf("Environment variable %s must be an integer", key))
+	if c.SocketMode && c.SlackAppToken == "" {
+		errs = append(errs, errors.New("slack_app_token is required in socket mode"))
+	}
+	if !c.SocketMode && c.SlackSigningSecret == "" {
+		errs = append(errs, errors.New("slack_signing_secret is required unless socket mode is on"))
+	}
+	if c.ServerPort < 1 || c.ServerPort > 65535 {
+		errs = append(errs, fmt.Errorf("server_port %d is out of range", c.ServerPort))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("log_level %q must be debug, info, warn or error", c.LogLevel))
+	}
+	if c.DatabasePath == "" {
+		errs = append(errs, errors.New("database_path is required"))
+	}
+	if c.WorkerCount < 1 {
+		errs = append(errs, errors.New("worker_count must be at least 1"))
+	}
+	if c.WorkQueueSize < 1 {
+		errs = append(errs, errors.New("work_queue_size must be at least 1"))
+	}
+	for name, d := range map[string]time.Duration{"job_timeout": c.JobTimeout, "shutdown_timeout": c.ShutdownTimeout, "idempotency_ttl": c.IdempotencyTTL} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+	if c.LifecycleInterval < 0 {
+		errs = append(errs, errors.New("lifecycle_interval must not be negative"))
+	}
+	// Leave room for the date and sequence within Slack's 80 character limit.
+	if !channelPrefixPattern.MatchString(c.ChannelPrefix) || len(c.ChannelPrefix) > 60 {
+		errs = append(errs, fmt.Errorf("channel_prefix %q must be up to 60 lower-case letters, digits, - or _", c.ChannelPrefix))
+	}
+	if err := validateChannelTemplate(c.ChannelTemplate); err != nil {
+		errs = append(errs, fmt.Errorf("channel_template %q: %w", c.ChannelTemplate, err))
+	}
+	if c.PostmortemTemplate != "" {
+		if _, err := loadPostmortemTemplate(c.PostmortemTemplate, c.Taxonomy); err != nil {
+			errs = append(errs, fmt.Errorf("postmortem_template: %w", err))
+		}
+	}
+	for i, hook := range c.Webhooks {
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: url %q must be an http or https URL", i, hook.URL))
+		}
+		if hook.Secret == "" {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: secret is required", i))
 		}
-		return defaultValue
+		for _, event := range hook.Events {
+			if !slices.Contains(webhookEvents, event) {
+				errs = append(errs, fmt.Errorf("webhooks[%d]: unknown event %q", i, event))
+			}
+		}
+	}
+	if c.WebhookMaxAttempts < 1 {
+		errs = append(errs, errors.New("webhook_max_attempts must be at least 1"))
+	}
+	if c.WebhookRetryDelay <= 0 {
+		errs = append(errs, errors.New("webhook_retry_delay must be positive"))
+	}
+	if c.Taxonomy == nil {
+		errs = append(errs, errors.New("taxonomy is required"))
+	} else if err := c.Taxonomy.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("taxonomy: %w", err))
+	} else if c.Alertmanager.Token != "" {
+		for value, severity := range c.Alertmanager.Severities {
+			if _, ok := c.Taxonomy.Severity(severity); !ok {
+				errs = append(errs, fmt.Errorf("alertmanager.severities: %q maps to unknown severity %q", value, severity))
+			}
+		}
+		if _, ok := c.Taxonomy.Severity(c.Alertmanager.DefaultSeverity); c.Alertmanager.DefaultSeverity != "" && !ok {
+			errs = append(errs, fmt.Errorf("alertmanager.default_severity %q is not a known severity", c.Alertmanager.DefaultSeverity))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SlogLevel is LogLevel as a slog.Level. It is info if LogLevel is invalid.
+func (c *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// envOverrides applies environment variables over config values, collecting
+// parse errors instead of failing on the first. Empty variables are ignored.
+type envOverrides struct {
+	errs []error
+}
+
+func (e *envOverrides) string(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
 	}
-	return value
 }
 
-func getEnvAsBool(key string, defaultValue bool, required bool) bool {
-	valueStr := getEnv(key, "", required)
-	if valueStr == "" {
-		return defaultValue
+func (e *envOverrides) int(key string, dst *int) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
 	}
-	value, err := strconv.ParseBool(valueStr)
+	parsed, err := strconv.Atoi(value)
 	if err != nil {
-		if required {
-			panic(fmt.Sprintf("Environment variable %s must be a boolean", key))
-		}
-		return defaultValue
+		e.errs = append(e.errs, fmt.Errorf("environment variable %s must be an integer", key))
+		return
+	}
+	*dst = parsed
+}
+
+func (e *envOverrides) bool(key string, dst *bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
 	}
-	return value
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("environment variable %s must be a boolean", key))
+		return
+	}
+	*dst = parsed
 }
 
-func getEnvAsDuration(key string, defaultValue time.Duration, required bool) time.Duration {
-	valueStr := getEnv(key, "", required)
-	if valueStr == "" {
-		return defaultValue
+func (e *envOverrides) duration(key string, dst *time.Duration) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("environment variable %s must be a valid duration", key))
+		return
 	}
-	value, err := time.ParseDuration(valueStr)
+	*dst = parsed
+}
+
+// ConfigHolder holds the current configuration. Reload swaps it atomically,
+// so work that already read the old configuration finishes with it.
+type ConfigHolder struct {
+	path    string
+	current atomic.Pointer[Config]
+}
+
+// NewConfigHolder holds config, which was loaded from path.
+func NewConfigHolder(path string, config *Config) *ConfigHolder {
+	h := &ConfigHolder{path: path}
+	h.current.Store(config)
+	return h
+}
+
+func (h *ConfigHolder) Get() *Config {
+	return h.current.Load()
+}
+
+// Reload loads the configuration again and makes it current. If it is
+// invalid the current configuration stays in place. Settings only read at
+// startup keep their running values, and a warning lists any that changed.
+func (h *ConfigHolder) Reload() (*Config, error) {
+	next, err := LoadConfig(h.path)
 	if err != nil {
-		if required {
-			panic(fmt.Sprintf("Environment variable %s must be a valid duration", key))
+		return nil, err
+	}
+	if changed := keepStartupSettings(next, h.Get()); len(changed) > 0 {
+		slog.Warn("Some configuration changes need a restart to take effect", "settings", changed)
+	}
+	h.current.Store(next)
+	return next, nil
+}
+
+// keepStartupSettings copies the settings that are only read at startup from
+// current to next and returns the names of those that differed.
+func keepStartupSettings(next, current *Config) []string {
+	var changed []string
+	keep := func(name string, differs bool, restore func()) {
+		if differs {
+			changed = append(changed, name)
+			restore()
 		}
-		return defaultValue
 	}
-	return value
+	keep("slack_token", next.SlackToken != current.SlackToken, func() { next.SlackToken = current.SlackToken })
+	keep("slack_signing_secret", next.SlackSigningSecret != current.SlackSigningSecret, func() { next.SlackSigningSecret = current.SlackSigningSecret })
+	keep("slack_app_token", next.SlackAppToken != current.SlackAppToken, func() { next.SlackAppToken = current.SlackAppToken })
+	keep("slack_socket_mode", next.SocketMode != current.SocketMode, func() { next.SocketMode = current.SocketMode })
+	keep("server_port", next.ServerPort != current.ServerPort, func() { next.ServerPort = current.ServerPort })
+	keep("server_host", next.ServerHost != current.ServerHost, func() { next.ServerHost = current.ServerHost })
+	keep("database_path", next.DatabasePath != current.DatabasePath, func() { next.DatabasePath = current.DatabasePath })
+	keep("worker_count", next.WorkerCount != current.WorkerCount, func() { next.WorkerCount = current.WorkerCount })
+	keep("work_queue_size", next.WorkQueueSize != current.WorkQueueSize, func() { next.WorkQueueSize = current.WorkQueueSize })
+	keep("job_timeout", next.JobTimeout != current.JobTimeout, func() { next.JobTimeout = current.JobTimeout })
+	keep("shutdown_timeout", next.ShutdownTimeout != current.ShutdownTimeout, func() { next.ShutdownTimeout = current.ShutdownTimeout })
+	keep("idempotency_ttl", next.IdempotencyTTL != current.IdempotencyTTL, func() { next.IdempotencyTTL = current.IdempotencyTTL })
+	keep("lifecycle_interval", next.LifecycleInterval != current.LifecycleInterval, func() { next.LifecycleInterval = current.LifecycleInterval })
+	keep("api_token", next.APIToken != current.APIToken, func() { next.APIToken = current.APIToken })
+	keep("alertmanager.token", next.Alertmanager.Token != current.Alertmanager.Token, func() { next.Alertmanager.Token = current.Alertmanager.Token })
+	return changed
 }
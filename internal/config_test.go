@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearConfigEnv stops the environment the tests run in from overriding the
+// config files they write.
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{"SLACK_TOKEN", "SLACK_SIGNING_SECRET", "SLACK_APP_TOKEN", "SLACK_SOCKET_MODE", "SERVER_PORT", "SERVER_HOST",
+		"LOG_LEVEL", "DATABASE_PATH", "WORKER_COUNT", "WORK_QUEUE_SIZE", "JOB_TIMEOUT", "SHUTDOWN_TIMEOUT", "IDEMPOTENCY_TTL",
+		"LIFECYCLE_INTERVAL", "CHANNEL_PREFIX", "CHANNEL_TEMPLATE", "TAXONOMY_PATH"} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("WORKER_COUNT", "8")
+
+	yamlPath := writeConfig(t, "hal.yaml", `
+slack_token: xoxb-file
+slack_signing_secret: secret
+server_port: 8080
+job_timeout: 90s
+channel_prefix: inc-
+taxonomy:
+  oncall: "<!subteam^S1>"
+  severities:
+    - id: P1
+      page_oncall: true
+`)
+	tomlPath := writeConfig(t, "hal.toml", `
+slack_token = "xoxb-file"
+slack_signing_secret = "secret"
+server_port = 8080
+job_timeout = "90s"
+channel_prefix = "inc-"
+
+[taxonomy]
+oncall = "<!subteam^S1>"
+
+[[taxonomy.severities]]
+id = "P1"
+page_oncall = true
+`)
+
+	for _, path := range []string{yamlPath, tomlPath} {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig(%s): %v", filepath.Base(path), err)
+		}
+		if cfg.ServerPort != 8080 || cfg.JobTimeout != 90*time.Second || cfg.ChannelPrefix != "inc-" || cfg.WorkerCount != 8 || cfg.IdempotencyTTL != 15*time.Minute {
+			t.Errorf("%s: unexpected config %+v", filepath.Base(path), cfg)
+		}
+		if level, ok := cfg.Taxonomy.Severity("P1"); !ok || level.Label != "P1" || !level.PageOnCall {
+			t.Errorf("%s: severity P1 = %+v", filepath.Base(path), level)
+		}
+		// Statuses the file doesn't mention keep their defaults.
+		if cfg.Taxonomy.InitialStatus() != StatusInvestigating {
+			t.Errorf("%s: statuses = %+v", filepath.Base(path), cfg.Taxonomy.Statuses)
+		}
+	}
+
+	_, err := LoadConfig(writeConfig(t, "bad.yaml", "server_port: 0\nlog_level: loud\nchannel_prefix: Incident\nchannel_template: \"{date}-{sev}\"\n"))
+	if err == nil {
+		t.Fatal("LoadConfig accepted an invalid config")
+	}
+	for _, want := range []string{"slack_token is required", "server_port 0", "log_level", "channel_prefix", "channel_template \"{date}-{sev}\": must contain {seq} or {id}"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	if _, err := LoadConfig(writeConfig(t, "typo.yaml", "slack_tokn: xoxb\n")); err == nil {
+		t.Error("LoadConfig accepted an unknown key")
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfig(t, "hal.yaml", "slack_token: xoxb\nslack_signing_secret: secret\n")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	configs := NewConfigHolder(path, cfg)
+
+	if err := os.WriteFile(path, []byte("slack_token: xoxb\nslack_signing_secret: secret\nserver_port: 9000\nchannel_prefix: inc-\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	next, err := configs.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if configs.Get() != next || next.ChannelPrefix != "inc-" {
+		t.Errorf("reloaded config not current: %+v", configs.Get())
+	}
+	if next.ServerPort != cfg.ServerPort {
+		t.Errorf("server_port changed on reload to %d", next.ServerPort)
+	}
+
+	if err := os.WriteFile(path, []byte("log_level: loud\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := configs.Reload(); err == nil {
+		t.Error("Reload accepted an invalid config")
+	}
+	if configs.Get() != next {
+		t.Error("invalid reload replaced the current config")
+	}
+}
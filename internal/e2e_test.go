@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/slack-go/slack"
+)
+
+const (
+	testSigningSecret     = "test-signing-secret"
+	testAlertmanagerToken = "test-alertmanager-token"
+	testAPIToken          = "test-api-token"
+)
+
+type testApp struct {
+	config   *Config
+	slack    *fakeSlack
+	store    *SQLStore
+	service  *IncidentService
+	queue    *WorkQueue
+	router   *gin.Engine
+	incident func() *Incident
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &Config{
+		SlackSigningSecret: testSigningSecret,
+		IdempotencyTTL:     time.Minute,
+		Taxonomy:           DefaultTaxonomy(),
+		WebhookMaxAttempts: 3,
+		WebhookRetryDelay:  10 * time.Millisecond,
+		APIToken:           testAPIToken,
+		Alertmanager: AlertmanagerConfig{
+			Token:         testAlertmanagerToken,
+			SeverityLabel: "severity",
+			Severities:    map[string]Severity{"critical": SeveritySev1},
+			Invite:        []string{"U0ONCALL"},
+		},
+	}
+
+	store, err := NewSQLStore(t.TempDir() + "/hal.db")
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	fake := newFakeSlack(t)
+	slackService := NewSlackService(fake.newClient(), cfg)
+	configs := NewConfigHolder("", cfg)
+	webhooks := NewWebhookDispatcher(configs, store, http.DefaultClient)
+	t.Cleanup(func() { webhooks.Shutdown(context.Background()) })
+	incidentService := NewIncidentService(slackService, store, configs, webhooks)
+	queue := NewWorkQueue(2, 10, 10*time.Second)
+	t.Cleanup(func() { queue.Shutdown(context.Background()) })
+
+	router := gin.New()
+	RegisterRoutes(router, incidentService, slackService, queue, cfg)
+
+	app := &testApp{config: cfg, slack: fake, store: store, service: incidentService, queue: queue, router: router}
+	app.incident = func() *Incident {
+		t.Helper()
+		incidents, err := store.ListIncidents(context.Background(), IncidentFilter{})
+		if err != nil || len(incidents) == 0 {
+			t.Fatalf("no incident stored (err=%v)", err)
+		}
+		return incidents[0]
+	}
+	return app
+}
+
+// incidentNamed returns the stored incident with the given description.
+// Submissions are processed concurrently, so incident numbers aren't
+// predictable.
+func (a *testApp) incidentNamed(t *testing.T, description string) *Incident {
+	t.Helper()
+	incidents, err := a.store.ListIncidents(context.Background(), IncidentFilter{})
+	if err != nil {
+		t.Fatalf("ListIncidents: %v", err)
+	}
+	for _, incident := range incidents {
+		if incident.Description == description {
+			return incident
+		}
+	}
+	t.Fatalf("no incident %q", description)
+	return nil
+}
+
+// post sends a Slack-signed form request to HAL.
+func (a *testApp) post(t *testing.T, path string, form url.Values, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	return a.send(t, path, "application/x-www-form-urlencoded", form.Encode(), headers...)
+}
+
+// event sends a Slack-signed Events API callback to HAL.
+func (a *testApp) event(t *testing.T, eventID string, event map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"type":     "event_callback",
+		"event_id": eventID,
+		"event":    event,
+	})
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	return a.send(t, "/events", "application/json", string(body))
+}
+
+func (a *testApp) send(t *testing.T, path, contentType, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", computeSignature(testSigningSecret, timestamp, []byte(body)))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST %s: status %d, body %s", path, rec.Code, rec.Body.String())
+	}
+	return rec
+}
+
+func (a *testApp) command(t *testing.T, channelID, text string) {
+	t.Helper()
+	a.post(t, "/incident", url.Values{
+		"command":      {"/incident"},
+		"text":         {text},
+		"channel_id":   {channelID},
+		"user_id":      {"U1"},
+		"user_name":    {"alice"},
+		"trigger_id":   {"trigger-" + strconv.FormatInt(time.Now().UnixNano(), 10)},
+		"response_url": {a.slack.responseURL()},
+	})
+}
+
+func (a *testApp) interaction(t *testing.T, interaction slack.InteractionCallback, headers ...string) {
+	t.Helper()
+	payload, err := json.Marshal(interaction)
+	if err != nil {
+		t.Fatalf("marshal interaction: %v", err)
+	}
+	a.post(t, "/interaction", url.Values{"payload": {string(payload)}}, headers...)
+}
+
+// eventually polls cond until it holds or the test times out, since HAL
+// processes requests on its background queue.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// archiveAfter sets a severity's archive grace period in the app's taxonomy.
+func (a *testApp) archiveAfter(severity Severity, d time.Duration) {
+	for i := range a.config.Taxonomy.Severities {
+		if a.config.Taxonomy.Severities[i].ID == severity {
+			a.config.Taxonomy.Severities[i].ArchiveAfter = d
+		}
+	}
+}
+
+func createIncidentSubmission(viewID, description, severity string) slack.InteractionCallback {
+	option := func(value string) slack.BlockAction {
+		return slack.BlockAction{SelectedOption: slack.OptionBlockObject{Value: value}}
+	}
+
+	var interaction slack.InteractionCallback
+	interaction.Type = slack.InteractionTypeViewSubmission
+	interaction.User = slack.User{ID: "U1", Name: "alice"}
+	interaction.View = slack.View{
+		ID:         viewID,
+		CallbackID: "create_incident_modal",
+		State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+			"description":          {"description": {Value: description}},
+			"status":               {"status": option(string(StatusInvestigating))},
+			"incident_severity":    {"incident_severity": option(severity)},
+			"incident_commander":   {"incident_commander": {SelectedUser: "U2"}},
+			"comms_representative": {"comms_representative": {}},
+			"incident_members":     {"incident_members": {SelectedUsers: []string{"U3"}}},
+		}},
+	}
+	return interaction
+}
+
+func TestIncidentLifecycle(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	app.command(t, "CGENERAL", "create")
+	views := app.slack.Calls("views.open")
+	if len(views) != 1 {
+		t.Fatalf("views.open calls = %d, want 1", len(views))
+	}
+	if view, _ := views[0].JSON["view"].(map[string]any); view["callback_id"] != "create_incident_modal" {
+		t.Fatalf("opened view %v, want create_incident_modal", views[0].JSON["view"])
+	}
+
+	app.interaction(t, createIncidentSubmission("V100", "Checkout is down", "SEV-1"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 2 && len(app.slack.Calls("chat.postEphemeral")) == 1
+	})
+
+	incident := app.incident()
+	if incident.Severity != SeveritySev1 || incident.Status != StatusInvestigating || incident.CommanderID != "U2" {
+		t.Fatalf("stored incident = %+v", incident)
+	}
+	channel := app.slack.Channel(incident.ChannelID)
+	if want := "SEV-1 incident: Checkout is down | Commander: <@U2>"; channel.Topic != want {
+		t.Fatalf("topic = %q, want %q", channel.Topic, want)
+	}
+	for _, member := range []string{"U1", "U2", "U3"} {
+		if !strings.Contains(strings.Join(channel.Members, ","), member) {
+			t.Errorf("%s was not invited; members %v", member, channel.Members)
+		}
+	}
+
+	// SEV-1 incidents get a postmortem action item for the commander.
+	items, err := app.store.ListActionItems(ctx, incident.ID)
+	if err != nil || len(items) != 1 || items[0].Description != "Create incident postmortem" || items[0].Assignee != "U2" {
+		t.Fatalf("action items = %+v, err %v", items, err)
+	}
+
+	app.command(t, incident.ChannelID, "t deploy rolled back")
+	eventually(t, "timeline entry", func() bool {
+		return strings.Contains(app.slack.PinnedText(incident.ChannelID), "deploy rolled back")
+	})
+
+	app.command(t, incident.ChannelID, "ai done 1")
+	eventually(t, "action item completion", func() bool {
+		item, err := app.store.GetActionItem(ctx, incident.ID, 1)
+		return err == nil && item.Completed && item.CompletedBy == "U1"
+	})
+
+	app.command(t, incident.ChannelID, "resolve rolled back the deploy")
+	// The topic is set after the incident is stored.
+	eventually(t, "resolution", func() bool {
+		return app.incident().Status == StatusResolved && strings.HasPrefix(app.slack.Channel(incident.ChannelID).Topic, "Resolved: Checkout is down")
+	})
+
+	incident = app.incident()
+	if incident.ResolvedAt == nil {
+		t.Fatal("resolved incident has no ResolvedAt")
+	}
+
+	timeline, err := app.store.ListTimelineItems(ctx, incident.ID)
+	if err != nil {
+		t.Fatalf("ListTimelineItems: %v", err)
+	}
+	var messages []string
+	for _, item := range timeline {
+		messages = append(messages, item.Message)
+	}
+	want := []string{
+		"Incident created. Severity: SEV-1 Status: Investigating",
+		"deploy rolled back",
+		"Incident resolved. Resolution: rolled back the deploy",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("timeline = %q, want %q", messages, want)
+	}
+}
+
+func TestRetriedCreateSubmissionCreatesOneIncident(t *testing.T) {
+	app := newTestApp(t)
+
+	submission := createIncidentSubmission("V200", "Search latency", "SEV-3")
+	app.interaction(t, submission)
+	app.interaction(t, submission, "X-Slack-Retry-Num", "1", "X-Slack-Retry-Reason", "http_timeout")
+
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 2
+	})
+	// Give a wrongly processed retry time to show up.
+	time.Sleep(100 * time.Millisecond)
+
+	if creates := app.slack.Calls("conversations.create"); len(creates) != 1 {
+		t.Fatalf("conversations.create calls = %d, want 1", len(creates))
+	}
+	incidents, err := app.store.ListIncidents(context.Background(), IncidentFilter{})
+	if err != nil || len(incidents) != 1 {
+		t.Fatalf("stored incidents = %d (err %v), want 1", len(incidents), err)
+	}
+}
+
+func TestTimelineSurvivesDeletedPin(t *testing.T) {
+	app := newTestApp(t)
+
+	app.interaction(t, createIncidentSubmission("V300", "Queue backlog", "SEV-2"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) >= 2
+	})
+	incident := app.incident()
+
+	// Simulate someone deleting the pinned timeline message.
+	app.slack.mu.Lock()
+	app.slack.channels[incident.ChannelID].Messages = nil
+	app.slack.mu.Unlock()
+
+	app.command(t, incident.ChannelID, "t consumers scaled up")
+	eventually(t, "timeline re-posted", func() bool {
+		text := app.slack.PinnedText(incident.ChannelID)
+		return strings.Contains(text, "Incident created") && strings.Contains(text, "consumers scaled up")
+	})
+}
+
+func TestEventsURLVerification(t *testing.T) {
+	app := newTestApp(t)
+
+	rec := app.send(t, "/events", "application/json", `{"type":"url_verification","token":"x","challenge":"abc123"}`)
+	var resp struct{ Challenge string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Challenge != "abc123" {
+		t.Fatalf("challenge response = %s (err %v)", rec.Body.String(), err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	app.interaction(t, createIncidentSubmission("V400", "Login errors", "SEV-3"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 2
+	})
+	incident := app.incident()
+
+	mention := map[string]any{"type": "app_mention", "user": "U1", "channel": incident.ChannelID, "text": "<@UHAL> timeline deploy rolled back", "ts": "1.1"}
+	app.event(t, "Ev1", mention)
+	app.event(t, "Ev1", mention) // Slack retry
+	eventually(t, "timeline entry from mention", func() bool {
+		items, _ := app.store.ListTimelineItems(ctx, incident.ID)
+		return len(items) == 2 && items[1].Message == "deploy rolled back" && items[1].User == "U1"
+	})
+
+	app.event(t, "Ev2", map[string]any{"type": "app_mention", "user": "U1", "channel": incident.ChannelID, "text": "<@UHAL> create"})
+	app.event(t, "Ev3", map[string]any{"type": "message", "channel_type": "im", "user": "U1", "channel": "D001", "text": "help"})
+	app.event(t, "Ev4", map[string]any{"type": "message", "channel_type": "im", "bot_id": "BHAL", "channel": "D001", "text": "help"})
+	eventually(t, "replies to mention and DM", func() bool {
+		return len(app.slack.Calls("chat.postEphemeral")) == 3 // help after setup, create refusal, DM help
+	})
+	if opened := app.slack.Calls("views.open"); len(opened) != 0 {
+		t.Fatalf("views.open called %d times from a mention", len(opened))
+	}
+
+	app.event(t, "Ev5", map[string]any{"type": "member_joined_channel", "user": "U9", "channel": incident.ChannelID})
+	eventually(t, "member recorded", func() bool {
+		members := app.incident().Members
+		return len(members) > 0 && members[len(members)-1] == "U9"
+	})
+
+	app.event(t, "Ev6", map[string]any{"type": "channel_archive", "user": "U1", "channel": incident.ChannelID})
+	eventually(t, "archive recorded", func() bool {
+		return app.incident().ArchivedAt != nil
+	})
+
+	// Events in channels that aren't incidents are ignored, without looking
+	// at their topic.
+	app.event(t, "Ev7", map[string]any{"type": "member_joined_channel", "user": "U9", "channel": "CRANDOM"})
+	time.Sleep(50 * time.Millisecond)
+	for _, call := range app.slack.Calls("conversations.info") {
+		if call.Form.Get("channel") == "CRANDOM" {
+			t.Error("looked up the topic of a channel that isn't an incident")
+		}
+	}
+
+	timeline, _ := app.store.ListTimelineItems(ctx, incident.ID)
+	if len(timeline) != 2 {
+		t.Fatalf("timeline has %d entries, want 2", len(timeline))
+	}
+}
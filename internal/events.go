@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// HandleEvent processes an Events API callback once per event ID; Slack
+// redelivers events it doesn't see acknowledged in time.
+func (s *IncidentService) HandleEvent(ctx context.Context, eventID string, event slackevents.EventsAPIInnerEvent) error {
+	key := ""
+	if eventID != "" {
+		key = "event:" + eventID
+	}
+	_, shared, err := s.idempotency.Do(ctx, key, func() (any, error) {
+		return nil, s.handleEvent(ctx, event)
+	})
+	if shared {
+		slog.InfoContext(ctx, "Skipped duplicate event", "eventID", eventID, "type", event.Type, "error", err)
+		return nil
+	}
+	return err
+}
+
+func (s *IncidentService) handleEvent(ctx context.Context, event slackevents.EventsAPIInnerEvent) error {
+	switch ev := event.Data.(type) {
+	case *slackevents.AppMentionEvent:
+		if ev.BotID != "" {
+			return nil
+		}
+		return s.handleTextCommand(ctx, ev.Channel, ev.User, commandFromMention(ev.Text))
+
+	case *slackevents.MessageEvent:
+		// Only direct messages from people; HAL's own replies arrive here too.
+		if ev.ChannelType != "im" || ev.BotID != "" || ev.SubType != "" || ev.User == "" {
+			return nil
+		}
+		return s.handleTextCommand(ctx, ev.Channel, ev.User, ev.Text)
+
+	case *slackevents.AppHomeOpenedEvent:
+		if ev.Tab != "home" {
+			return nil
+		}
+		return s.PublishHome(ctx, ev.User)
+
+	case *slackevents.MemberJoinedChannelEvent:
+		return s.withChannelIncident(ctx, ev.Channel, func(incident *Incident) error {
+			members := appendIfMissing(incident.Members, ev.User)
+			if len(members) == len(incident.Members) {
+				return nil
+			}
+			incident.Members = members
+			return s.store.UpdateIncident(ctx, incident)
+		})
+
+	case *slackevents.ChannelArchiveEvent:
+		return s.withChannelIncident(ctx, ev.Channel, func(incident *Incident) error {
+			now := time.Now().UTC()
+			incident.ArchivedAt = &now
+			return s.store.UpdateIncident(ctx, incident)
+		})
+
+	case *slackevents.ChannelUnarchiveEvent:
+		return s.withChannelIncident(ctx, ev.Channel, func(incident *Incident) error {
+			incident.ArchivedAt = nil
+			return s.store.UpdateIncident(ctx, incident)
+		})
+
+	default:
+		slog.DebugContext(ctx, "Ignoring event", "type", event.Type)
+	}
+
+	return nil
+}
+
+// handleTextCommand runs a command typed in a message rather than through the
+// slash command. Messages carry no trigger ID, so commands that open a modal
+// are turned away.
+func (s *IncidentService) handleTextCommand(ctx context.Context, channelID, userID, text string) error {
+	target := ResponseTarget{ChannelID: channelID, UserID: userID}
+	if commandOpensModal(text) {
+		command, _ := parseCommand(text)
+		return s.slackService.RespondText(ctx, target, fmt.Sprintf("`%s` needs a dialog, which I can only open from the slash command. Use `/incident %s` instead.", command, command))
+	}
+
+	err := s.handleCommand(ctx, SlackCommandRequest{ChannelId: channelID, UserId: userID, Text: text})
+	if err != nil {
+		s.ReportFailure(ctx, target, err)
+	}
+	return err
+}
+
+// withChannelIncident runs fn under the incident lock if the channel is an
+// incident channel HAL already knows about, and does nothing otherwise. These
+// events fire in every channel HAL is in, so unlike commands they don't adopt
+// channels from their topic.
+func (s *IncidentService) withChannelIncident(ctx context.Context, channelID string, fn func(incident *Incident) error) error {
+	incident, err := s.store.GetIncidentByChannel(ctx, channelID)
+	if errors.Is(err, ErrIncidentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+	return s.withIncidentID(ctx, incident.ID, fn)
+}
+
+// commandFromMention returns the text following the first user mention, so
+// "<@U123> timeline deploy rolled back" becomes "timeline deploy rolled back".
+func commandFromMention(text string) string {
+	start := strings.Index(text, "<@")
+	if start < 0 {
+		return strings.TrimSpace(text)
+	}
+	end := strings.Index(text[start:], ">")
+	if end < 0 {
+		return strings.TrimSpace(text)
+	}
+	return strings.TrimSpace(text[start+end+1:])
+}
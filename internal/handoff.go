@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	acceptHandoffActionID  = "accept_handoff"
+	declineHandoffActionID = "decline_handoff"
+	// handoffTimelineEntries is how many of the latest timeline entries the
+	// handoff briefing includes.
+	handoffTimelineEntries = 5
+)
+
+var (
+	// ErrHandoffStale is returned when a handoff is answered after the
+	// commander it would replace has already changed.
+	ErrHandoffStale = errors.New("handoff is out of date")
+	// ErrHandoffNotForUser is returned when someone other than the incoming
+	// commander answers a handoff.
+	ErrHandoffNotForUser = errors.New("handoff is for someone else")
+	// ErrIncidentResolved is returned when a handoff is requested for an
+	// incident that no longer needs a commander.
+	ErrIncidentResolved = errors.New("incident is resolved")
+)
+
+// handoffRequest is carried in the value of the briefing's buttons, so an
+// answer can be checked against the incident as it is now.
+type handoffRequest struct {
+	IncidentID  string `json:"incident"`
+	From        string `json:"from,omitempty"`
+	To          string `json:"to"`
+	RequestedBy string `json:"by"`
+}
+
+func parseHandoffRequest(value string) (handoffRequest, error) {
+	var request handoffRequest
+	if err := json.Unmarshal([]byte(value), &request); err != nil {
+		return handoffRequest{}, fmt.Errorf("failed to decode handoff: %w", err)
+	}
+	return request, nil
+}
+
+// RequestHandoff sends the incoming commander a briefing on the incident with
+// buttons to accept or decline taking over. The role only changes hands once
+// they accept. It returns ErrIncidentResolved for resolved incidents, and
+// ErrIncidentConfidential if the incident is confidential and the incoming
+// commander isn't involved in it, since the briefing would show it to them.
+func (s *IncidentService) RequestHandoff(ctx context.Context, channelID, requesterID, toUserID string) (*Incident, error) {
+	incident, err := s.incidentForChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Status == StatusResolved {
+		return nil, ErrIncidentResolved
+	}
+	if incident.CommanderID == toUserID {
+		return nil, ErrRoleUnchanged
+	}
+	if incident.Confidential {
+		allowed := canView(incident, toUserID)
+		s.audit(ctx, incident, AuditHandoff, toUserID, allowed, "requested by "+requesterID)
+		if !allowed {
+			return nil, ErrIncidentConfidential
+		}
+	}
+
+	request := handoffRequest{IncidentID: incident.ID, From: incident.CommanderID, To: toUserID, RequestedBy: requesterID}
+	blocks, err := s.handoffBriefing(ctx, incident, request, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.slackService.PostMessage(ctx, toUserID, blocks); err != nil {
+		return nil, fmt.Errorf("failed to send handoff briefing: %w", err)
+	}
+	return incident, nil
+}
+
+// handoffBriefing tells the incoming commander what they'd be taking on: the
+// incident's status, severity and age, its latest timeline entries and its
+// open action items.
+func (s *IncidentService) handoffBriefing(ctx context.Context, incident *Incident, request handoffRequest, now time.Time) ([]slack.Block, error) {
+	timeline, err := s.store.ListTimelineItems(ctx, incident.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timeline: %w", err)
+	}
+	items, err := s.store.ListActionItems(ctx, incident.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list action items: %w", err)
+	}
+	value, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode handoff: %w", err)
+	}
+
+	taxonomy := s.config().Taxonomy
+	role, _ := taxonomy.Role(RoleCommander)
+	commander := "_Unassigned_"
+	if incident.CommanderID != "" {
+		commander = fmt.Sprintf("<@%s>", incident.CommanderID)
+	}
+
+	mrkdwn := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject("mrkdwn", text, false, false)
+	}
+	intro := fmt.Sprintf(":handshake: <@%s> would like to hand over *%s* of %s in <#%s> to you.\n>%s", request.RequestedBy, role.Label, incident.ID, incident.ChannelID, incident.Description)
+	blocks := []slack.Block{
+		slack.NewSectionBlock(mrkdwn(intro), []*slack.TextBlockObject{
+			mrkdwn("*Status*\n" + taxonomy.StatusLabel(incident.Status)),
+			mrkdwn("*Severity*\n" + taxonomy.SeverityLabel(incident.Severity)),
+			mrkdwn("*Elapsed*\n" + formatAge(now.Sub(incident.CreatedAt))),
+			mrkdwn("*Current " + role.Label + "*\n" + commander),
+		}, nil),
+	}
+
+	lines := []string{"*Latest timeline*"}
+	for _, item := range timeline[max(len(timeline)-handoffTimelineEntries, 0):] {
+		lines = append(lines, timelineLine(item))
+	}
+	if len(timeline) == 0 {
+		lines = append(lines, "_Nothing yet._")
+	}
+	blocks = append(blocks, slack.NewSectionBlock(mrkdwn(strings.Join(lines, "\n")), nil, nil))
+
+	lines = []string{"*Open action items*"}
+	for _, item := range items {
+		if !item.Completed {
+			lines = append(lines, actionItemLine(item))
+		}
+	}
+	if len(lines) == 1 {
+		lines = append(lines, "_None._")
+	}
+	blocks = append(blocks, slack.NewSectionBlock(mrkdwn(strings.Join(lines, "\n")), nil, nil))
+
+	accept := slack.NewButtonBlockElement(acceptHandoffActionID, string(value), slack.NewTextBlockObject("plain_text", "Accept", false, false))
+	accept.Style = slack.StylePrimary
+	decline := slack.NewButtonBlockElement(declineHandoffActionID, string(value), slack.NewTextBlockObject("plain_text", "Decline", false, false))
+	decline.Style = slack.StyleDanger
+	blocks = append(blocks, slack.NewActionBlock("", accept, decline))
+	return blocks, nil
+}
+
+// AnswerHandoff applies the incoming commander's answer to a handoff. If
+// they accept, they become the commander; either way the answer goes on the
+// timeline and the requester is told. It returns ErrHandoffStale if the
+// commander has changed since the handoff was requested, and
+// ErrIncidentResolved if the incident has been resolved.
+func (s *IncidentService) AnswerHandoff(ctx context.Context, request handoffRequest, userID string, accepted bool) (*Incident, error) {
+	if userID != request.To {
+		return nil, ErrHandoffNotForUser
+	}
+	var answered *Incident
+	err := s.withIncidentID(ctx, request.IncidentID, func(incident *Incident) error {
+		if incident.Status == StatusResolved {
+			return ErrIncidentResolved
+		}
+		if incident.CommanderID != request.From {
+			return ErrHandoffStale
+		}
+		answered = incident
+		role, _ := s.config().Taxonomy.Role(RoleCommander)
+
+		if !accepted {
+			message := fmt.Sprintf("%s handoff declined.", role.Label)
+			if err := s.addTimelineItem(ctx, incident, userID, message); err != nil {
+				return fmt.Errorf("failed to add timeline item: %w", err)
+			}
+			s.notifyHandoffRequester(ctx, incident, request, fmt.Sprintf("<@%s> declined to take over %s as %s.", userID, incident.ID, role.Label))
+			return nil
+		}
+
+		message := fmt.Sprintf("%s handed off to <@%s>.", role.Label, userID)
+		if request.From != "" {
+			message = fmt.Sprintf("%s handed off from <@%s> to <@%s>.", role.Label, request.From, userID)
+		}
+		if err := s.assignRole(ctx, incident, userID, RoleCommander, userID, message); err != nil {
+			return err
+		}
+		s.notifyHandoffRequester(ctx, incident, request, fmt.Sprintf("<@%s> accepted and is now the %s of %s.", userID, role.Label, incident.ID))
+		return nil
+	})
+	return answered, err
+}
+
+// notifyHandoffRequester DMs whoever asked for a handoff how it went. The
+// answer is on the timeline they can already see, so a failed DM is logged
+// and the handoff stands.
+func (s *IncidentService) notifyHandoffRequester(ctx context.Context, incident *Incident, request handoffRequest, text string) {
+	if request.RequestedBy == "" || request.RequestedBy == request.To {
+		return
+	}
+	_, err := s.slackService.PostMessage(ctx, request.RequestedBy, []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to tell requester about handoff", "incidentID", incident.ID, "userID", request.RequestedBy, "error", err)
+	}
+}
+
+// handleHandoffAnswer handles the briefing's buttons, replacing the briefing
+// with the outcome so it can't be answered twice.
+func (s *IncidentService) handleHandoffAnswer(ctx context.Context, interaction slack.InteractionCallback, action *slack.BlockAction) error {
+	request, err := parseHandoffRequest(action.Value)
+	if err != nil {
+		return err
+	}
+	accepted := action.ActionID == acceptHandoffActionID
+	incident, err := s.AnswerHandoff(ctx, request, interaction.User.ID, accepted)
+
+	var outcome string
+	switch {
+	case errors.Is(err, ErrHandoffStale):
+		outcome = fmt.Sprintf("This handoff of %s is out of date: the commander has changed since it was sent.", request.IncidentID)
+	case errors.Is(err, ErrIncidentResolved):
+		outcome = fmt.Sprintf("%s has been resolved since this handoff was sent, so there's nothing to take over.", request.IncidentID)
+	case err != nil:
+		return fmt.Errorf("failed to answer handoff of %s: %w", request.IncidentID, err)
+	case accepted:
+		outcome = fmt.Sprintf(":white_check_mark: You're now the commander of %s. Head over to <#%s>.", incident.ID, incident.ChannelID)
+	default:
+		outcome = fmt.Sprintf("You declined the handoff of %s.", incident.ID)
+	}
+
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", outcome, false, false), nil, nil)}
+	if interaction.Message.Timestamp != "" {
+		err = s.slackService.UpdateMessage(ctx, interaction.Channel.ID, interaction.Message.Timestamp, blocks)
+	} else {
+		target := ResponseTarget{ResponseURL: interaction.ResponseURL, ChannelID: interaction.Channel.ID, UserID: interaction.User.ID}
+		err = s.slackService.Respond(ctx, target, blocks...)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to show handoff outcome", "incidentID", request.IncidentID, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// answerHandoff clicks a button on the handoff briefing in userID's DMs.
+func (a *testApp) answerHandoff(t *testing.T, userID, actionID string) {
+	t.Helper()
+	messages := a.slack.Messages(userID)
+	briefing := messages[len(messages)-1]
+	var blocks []map[string]any
+	if err := json.Unmarshal(briefing.Blocks, &blocks); err != nil {
+		t.Fatalf("decode briefing: %v", err)
+	}
+	var value string
+	for _, element := range blocks[len(blocks)-1]["elements"].([]any) {
+		if button := element.(map[string]any); button["action_id"] == actionID {
+			value = button["value"].(string)
+		}
+	}
+
+	var click slack.InteractionCallback
+	click.Type = slack.InteractionTypeBlockActions
+	click.TriggerID = "T-" + actionID + "-" + briefing.TS
+	click.User = slack.User{ID: userID}
+	click.Channel = slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: userID}}}
+	click.Message = slack.Message{Msg: slack.Msg{Timestamp: briefing.TS}}
+	click.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: actionID, Value: value}}
+	a.interaction(t, click)
+}
+
+func TestHandoff(t *testing.T) {
+	app := newTestApp(t)
+
+	app.interaction(t, createIncidentSubmission("V1700", "Payments are failing", "SEV-1"))
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	incident := app.incident()
+	app.command(t, incident.ChannelID, "ai Refund failed payments")
+	app.command(t, incident.ChannelID, "timeline Rolled back the gateway deploy")
+	eventually(t, "notes", func() bool {
+		return strings.Contains(app.slack.PinnedText(incident.ChannelID), "Rolled back the gateway deploy")
+	})
+
+	// U2 is the commander and hands off to U5, who declines.
+	app.command(t, incident.ChannelID, "handoff <@U5|erin>")
+	eventually(t, "briefing", func() bool { return len(app.directMessages("U5")) == 1 })
+	briefing := app.directMessages("U5")[0]
+	for _, want := range []string{"Payments are failing", "SEV-1", "Investigating", "*Elapsed*", "Rolled back the gateway deploy", "Refund failed payments", "\\u003c@U2\\u003e", acceptHandoffActionID} {
+		if !strings.Contains(briefing, want) {
+			t.Errorf("briefing missing %q: %s", want, briefing)
+		}
+	}
+	app.answerHandoff(t, "U5", declineHandoffActionID)
+	eventually(t, "decline", func() bool {
+		return strings.Contains(app.slack.PinnedText(incident.ChannelID), "Incident Commander handoff declined")
+	})
+	if app.incident().CommanderID != "U2" {
+		t.Errorf("commander changed on decline")
+	}
+
+	// The second time they accept.
+	app.command(t, incident.ChannelID, "handoff <@U5>")
+	eventually(t, "second briefing", func() bool { return len(app.directMessages("U5")) == 2 })
+	app.answerHandoff(t, "U5", acceptHandoffActionID)
+	// Replacing the briefing is the last thing HAL does.
+	eventually(t, "handoff", func() bool {
+		return strings.Contains(string(app.slack.Messages("U5")[1].Blocks), "now the commander")
+	})
+	if pinned := app.slack.PinnedText(incident.ChannelID); !strings.Contains(pinned, "Incident Commander handed off from \\u003c@U2\\u003e to \\u003c@U5\\u003e.") {
+		t.Errorf("timeline = %s", pinned)
+	}
+	incident = app.incident()
+	if incident.CommanderID != "U5" || !slices.Contains(incident.Members, "U5") {
+		t.Errorf("incident after handoff = %+v", incident)
+	}
+	if !strings.Contains(app.slack.Channel(incident.ChannelID).Topic, "Commander: <@U5>") {
+		t.Errorf("topic = %q", app.slack.Channel(incident.ChannelID).Topic)
+	}
+	if notes := app.directMessages("U1"); len(notes) != 2 || !strings.Contains(notes[1], "accepted") {
+		t.Errorf("requester DMs = %v", notes)
+	}
+	// U5 was sent the commander checklist.
+	messages := app.slack.Messages("U5")
+	if checklist := string(messages[len(messages)-1].Blocks); !strings.Contains(checklist, "Checklist") {
+		t.Errorf("last DM = %s", checklist)
+	}
+}
+
+func TestHandoffRefused(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	submission := createIncidentSubmission("V1701", "Leaked API keys", "SEV-1")
+	submission.View.State.Values["confidential"] = map[string]slack.BlockAction{
+		"confidential": {SelectedOptions: []slack.OptionBlockObject{{Value: "confidential"}}},
+	}
+	app.interaction(t, submission)
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	incident := app.incident()
+	replies := func() []string {
+		var texts []string
+		for _, call := range app.slack.Calls("response_url") {
+			blocks, _ := json.Marshal(call.JSON["blocks"])
+			texts = append(texts, string(blocks))
+		}
+		return texts
+	}
+
+	// U9 isn't involved in the confidential incident, so isn't briefed.
+	app.command(t, incident.ChannelID, "handoff <@U9>")
+	eventually(t, "confidential reply", func() bool { return len(replies()) == 1 })
+	if reply := replies()[0]; !strings.Contains(reply, "isn't part of this confidential incident") {
+		t.Errorf("reply = %s", reply)
+	}
+	// U3 was added as a member, so is.
+	app.command(t, incident.ChannelID, "handoff <@U3>")
+	eventually(t, "briefing", func() bool { return len(app.directMessages("U3")) == 1 })
+	if dms := app.directMessages("U9"); len(dms) != 0 {
+		t.Errorf("U9 was sent %v", dms)
+	}
+	entries, _ := app.store.ListAuditEntries(ctx, incident.ID)
+	var handoffs []AuditEntry
+	for _, entry := range entries {
+		if entry.Action == AuditHandoff {
+			handoffs = append(handoffs, entry)
+		}
+	}
+	if len(handoffs) != 2 || handoffs[0].User != "U9" || handoffs[0].Allowed || handoffs[1].User != "U3" || !handoffs[1].Allowed {
+		t.Errorf("handoff audit entries = %+v", handoffs)
+	}
+
+	app.command(t, incident.ChannelID, "resolve")
+	eventually(t, "resolution", func() bool { return app.incident().Status == StatusResolved })
+	app.command(t, incident.ChannelID, "handoff <@U3>")
+	eventually(t, "resolved reply", func() bool {
+		texts := replies()
+		return strings.Contains(texts[len(texts)-1], "This incident is resolved")
+	})
+	if dms := app.directMessages("U3"); len(dms) != 1 {
+		t.Errorf("U3 was briefed on a resolved incident: %v", dms)
+	}
+
+	// Accepting the briefing sent before the resolution changes nothing.
+	app.answerHandoff(t, "U3", acceptHandoffActionID)
+	eventually(t, "resolved outcome", func() bool {
+		return strings.Contains(string(app.slack.Messages("U3")[0].Blocks), "has been resolved since this handoff was sent")
+	})
+	if app.incident().CommanderID != "U2" {
+		t.Errorf("commander changed after resolution: %s", app.incident().CommanderID)
+	}
+	if strings.Contains(app.slack.PinnedText(incident.ChannelID), "handed off") {
+		t.Errorf("timeline = %s", app.slack.PinnedText(incident.ChannelID))
+	}
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	declareIncidentActionID = "declare_incident"
+
+	maxHomeIncidents   = 20
+	maxHomeActionItems = 20
+	homeResolvedCount  = 5
+)
+
+// PublishHome renders a user's App Home tab: a button to declare an incident,
+// the open incidents they're part of, the open action items assigned to them
+// and recently resolved incidents they may see.
+func (s *IncidentService) PublishHome(ctx context.Context, userID string) error {
+	open, err := s.store.ListIncidents(ctx, IncidentFilter{Statuses: s.config().Taxonomy.OpenStatuses()})
+	if err != nil {
+		return fmt.Errorf("failed to list open incidents: %w", err)
+	}
+	var mine []*Incident
+	for _, incident := range open {
+		if isInvolved(incident, userID) {
+			mine = append(mine, incident)
+		}
+	}
+
+	items, err := s.store.ListOpenActionItemsByAssignee(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list action items: %w", err)
+	}
+	channels := make(map[string]string)
+	for _, item := range items {
+		if _, ok := channels[item.IncidentID]; ok {
+			continue
+		}
+		incident, err := s.store.GetIncident(ctx, item.IncidentID)
+		if err != nil {
+			return fmt.Errorf("failed to load incident %s: %w", item.IncidentID, err)
+		}
+		channels[item.IncidentID] = incident.ChannelID
+	}
+
+	resolved, err := s.listVisibleIncidents(ctx, IncidentFilter{Statuses: []Status{StatusResolved}, Limit: homeResolvedCount}, userID, AuditList)
+	if err != nil {
+		return fmt.Errorf("failed to list resolved incidents: %w", err)
+	}
+
+	view := slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: homeBlocks(s.config().Taxonomy, mine, items, channels, resolved, time.Now())},
+	}
+	return s.slackService.PublishHomeView(ctx, userID, view)
+}
+
+// isInvolved reports whether the user holds a role in or was added to an
+// incident.
+func isInvolved(incident *Incident, userID string) bool {
+	if incident.CommanderID == userID || incident.CommsRepID == userID || incident.CreatedBy == userID {
+		return true
+	}
+	for _, holder := range incident.Roles {
+		if holder == userID {
+			return true
+		}
+	}
+	for _, member := range incident.Members {
+		if member == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func homeBlocks(taxonomy *Taxonomy, mine []*Incident, items []ActionItem, channels map[string]string, resolved []*Incident, now time.Time) []slack.Block {
+	mrkdwn := func(text string) *slack.SectionBlock {
+		return slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)
+	}
+
+	declareButton := slack.NewButtonBlockElement(declareIncidentActionID, "", slack.NewTextBlockObject("plain_text", "🚨 Declare incident", true, false))
+	declareButton.Style = slack.StyleDanger
+
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "Incidents", false, false)),
+		slack.NewActionBlock("home_actions", declareButton),
+		slack.NewDividerBlock(),
+		mrkdwn("*Your active incidents*"),
+	}
+
+	if len(mine) == 0 {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", "You're not part of any open incident.", false, false)))
+	}
+	for i, incident := range mine {
+		if i == maxHomeIncidents {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("…and %d more. Use `/incident list` to see them all.", len(mine)-i), false, false)))
+			break
+		}
+		blocks = append(blocks, mrkdwn(incidentListLine(taxonomy, incident, now)))
+	}
+
+	blocks = append(blocks, slack.NewDividerBlock(), mrkdwn("*Action items assigned to you*"))
+	if len(items) == 0 {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", "You have no open action items assigned to you.", false, false)))
+	} else {
+		lines := make([]string, 0, min(len(items), maxHomeActionItems)+1)
+		for i, item := range items {
+			if i == maxHomeActionItems {
+				lines = append(lines, fmt.Sprintf("…and %d more.", len(items)-i))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("• <#%s> %d. %s", channels[item.IncidentID], item.Number, item.Description))
+		}
+		blocks = append(blocks, mrkdwn(strings.Join(lines, "\n")))
+	}
+
+	blocks = append(blocks, slack.NewDividerBlock(), mrkdwn("*Recently resolved*"))
+	if len(resolved) == 0 {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", "No incidents have been resolved yet.", false, false)))
+	}
+	for _, incident := range resolved {
+		blocks = append(blocks, mrkdwn(incidentListLine(taxonomy, incident, now)))
+	}
+
+	return blocks
+}
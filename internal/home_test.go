@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestAppHome(t *testing.T) {
+	app := newTestApp(t)
+
+	app.interaction(t, createIncidentSubmission("V700", "Checkout is down", "SEV-1"))
+	app.interaction(t, createIncidentSubmission("V701", "Search is slow", "SEV-3"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 4
+	})
+	// Only items assigned to U1 are listed; the postmortem items are the
+	// commander's.
+	open := app.incidentNamed(t, "Checkout is down")
+	app.command(t, open.ChannelID, "ai <@U1|alice> Rotate the API keys")
+	app.command(t, open.ChannelID, "ai Check the other regions")
+	eventually(t, "action items", func() bool {
+		items, _ := app.store.ListActionItems(context.Background(), open.ID)
+		return len(items) == 3
+	})
+	resolved := app.incidentNamed(t, "Search is slow")
+	app.command(t, resolved.ChannelID, "resolve")
+	eventually(t, "resolution", func() bool {
+		return app.incidentNamed(t, "Search is slow").Status == StatusResolved
+	})
+
+	app.event(t, "EvHome1", map[string]any{"type": "app_home_opened", "user": "U1", "tab": "home"})
+	app.event(t, "EvHome2", map[string]any{"type": "app_home_opened", "user": "U1", "tab": "messages"})
+	eventually(t, "home published", func() bool {
+		return len(app.slack.Calls("views.publish")) == 1
+	})
+
+	call := app.slack.Calls("views.publish")[0]
+	if call.JSON["user_id"] != "U1" {
+		t.Fatalf("published home for %v, want U1", call.JSON["user_id"])
+	}
+	view, _ := json.Marshal(call.JSON["view"])
+	text := string(view)
+	for _, want := range []string{declareIncidentActionID, "Checkout is down", "Recently resolved", "Search is slow", "Rotate the API keys"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("home view is missing %q: %s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"Create incident postmortem", "Check the other regions"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("home view lists %q, which isn't assigned to U1", unwanted)
+		}
+	}
+
+	// The declare button opens the create modal right away.
+	var click slack.InteractionCallback
+	click.Type = slack.InteractionTypeBlockActions
+	click.TriggerID = "T-home"
+	click.User = slack.User{ID: "U2"}
+	click.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: declareIncidentActionID}}
+	app.interaction(t, click)
+	if opened := app.slack.Calls("views.open"); len(opened) != 1 {
+		t.Fatalf("views.open calls = %d, want 1", len(opened))
+	}
+}
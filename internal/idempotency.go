@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var errIdempotentCallPanicked = errors.New("idempotent call panicked")
+
+// IdempotencyCache remembers the outcome of work keyed by an ID Slack reuses
+// when it redelivers a request (view ID, trigger ID, event ID), so retries
+// don't repeat side effects such as creating a second incident channel.
+type IdempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	done    chan struct{}
+	result  any
+	err     error
+	expires time.Time
+}
+
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Do runs fn once per key within the TTL. Callers that arrive while fn is
+// running, or after it succeeded, get the original result with shared set.
+// A failed call is forgotten so that a later retry can try again. An empty
+// key disables de-duplication.
+func (c *IdempotencyCache) Do(ctx context.Context, key string, fn func() (any, error)) (result any, shared bool, err error) {
+	if key == "" {
+		result, err = fn()
+		return result, false, err
+	}
+
+	c.mu.Lock()
+	c.evictExpired()
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-entry.done:
+			return entry.result, true, entry.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	entry := &idempotencyEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			entry.err = errIdempotentCallPanicked
+		}
+		c.mu.Lock()
+		if entry.err != nil {
+			delete(c.entries, key)
+		} else {
+			entry.expires = c.now().Add(c.ttl)
+		}
+		c.mu.Unlock()
+		close(entry.done)
+	}()
+
+	entry.result, entry.err = fn()
+	completed = true
+	return entry.result, false, entry.err
+}
+
+// evictExpired drops completed entries past their TTL. Callers must hold mu.
+func (c *IdempotencyCache) evictExpired() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
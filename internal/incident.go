@@ -2,9 +2,11 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strconv"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,20 +15,37 @@ import (
 
 type IncidentService struct {
 	slackService *SlackService
-	config       *Config
+	store        IncidentStore
+	configs      *ConfigHolder
+	webhooks     *WebhookDispatcher
+	locks        keyedMutex
+	idempotency  *IdempotencyCache
 }
 
-func NewIncidentService(slackService *SlackService, config *Config) *IncidentService {
+// NewIncidentService creates the service. webhooks may be nil to publish no
+// webhooks.
+func NewIncidentService(slackService *SlackService, store IncidentStore, configs *ConfigHolder, webhooks *WebhookDispatcher) *IncidentService {
 	return &IncidentService{
 		slackService: slackService,
-		config:       config,
+		store:        store,
+		configs:      configs,
+		webhooks:     webhooks,
+		idempotency:  NewIdempotencyCache(configs.Get().IdempotencyTTL),
 	}
 }
 
-func (s *IncidentService) CreateIncidentChannel(ctx context.Context, description string, severity Severity, status Status, incidentCommanderID string, commsRepresentativeID string, userIDs ...string) (*slack.Channel, error) {
-	channelInt := 1
-	var channel *slack.Channel
-	var err error
+// config returns the current configuration. Read it once per operation so a
+// reload midway doesn't mix old and new settings.
+func (s *IncidentService) config() *Config {
+	return s.configs.Get()
+}
+
+// CreateIncidentChannel stores a new incident and creates its channel, named
+// from the channel template. The incident is stored first so the template can
+// use its ID, and removed again if the channel can't be set up. Confidential
+// incidents get a private channel.
+func (s *IncidentService) CreateIncidentChannel(ctx context.Context, createdBy, description string, severity Severity, status Status, incidentCommanderID string, commsRepresentativeID string, confidential bool, userIDs ...string) (*slack.Channel, error) {
+	config := s.config()
 
 	// Ensure commander and comms rep are in the userIDs to be invited, if they are set
 	// The main userIDs array already includes the person who initiated the command + any from the multi-select
@@ -38,175 +57,521 @@ func (s *IncidentService) CreateIncidentChannel(ctx context.Context, description
 		activeUserIDs = appendIfMissing(activeUserIDs, commsRepresentativeID)
 	}
 
-	for channelInt < 10 {
-		channelName := "incident-" + time.Now().Format("20060102") + "-" + strconv.Itoa(channelInt)
-		channel, err = s.slackService.CreateChannel(ctx, channelName, false)
-
-		if err != nil {
-			if strings.Contains(err.Error(), "name_taken") {
-				channelInt++
-				continue
+	incident := &Incident{
+		Description:  description,
+		Status:       status,
+		Severity:     severity,
+		CommanderID:  incidentCommanderID,
+		CommsRepID:   commsRepresentativeID,
+		CreatedBy:    createdBy,
+		Members:      activeUserIDs,
+		Confidential: confidential,
+	}
+	if status == StatusResolved {
+		now := time.Now().UTC()
+		incident.ResolvedAt = &now
+	}
+	err := s.store.CreateIncident(ctx, incident)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store incident: %w", err)
+	}
+	// abandon undoes a declaration that failed part way. A channel that was
+	// already created is archived, so it isn't left behind without an
+	// incident.
+	var channel *slack.Channel
+	abandon := func(err error) (*slack.Channel, error) {
+		if channel != nil {
+			if archiveErr := s.slackService.ArchiveChannel(ctx, channel.ID); archiveErr != nil {
+				slog.WarnContext(ctx, "Failed to archive channel of abandoned incident", "incidentID", incident.ID, "channelID", channel.ID, "error", archiveErr)
 			}
-			return nil, fmt.Errorf("failed to create incident channel: %w", err)
 		}
-
-		if len(activeUserIDs) > 0 {
-			err = s.slackService.InviteUsersToChannel(ctx, channel.ID, activeUserIDs...)
-			if err != nil {
-				return nil, fmt.Errorf("failed to invite users to incident channel: %w", err)
-			}
+		if deleteErr := s.store.DeleteIncident(ctx, incident.ID); deleteErr != nil {
+			slog.WarnContext(ctx, "Failed to remove incident without a channel", "incidentID", incident.ID, "error", deleteErr)
 		}
+		return nil, err
+	}
 
-		// Construct topic string
-		topicParts := []string{fmt.Sprintf("%s incident: %s", severity, description)}
-		if incidentCommanderID != "" {
-			topicParts = append(topicParts, fmt.Sprintf("Commander: <@%s>", incidentCommanderID))
-		}
-		if commsRepresentativeID != "" {
-			topicParts = append(topicParts, fmt.Sprintf("Comms: <@%s>", commsRepresentativeID))
-		}
-		channelTopic := strings.Join(topicParts, " | ")
+	channel, err = s.createNamedChannel(ctx, config, incident)
+	if err != nil {
+		return abandon(err)
+	}
 
-		err = s.slackService.SetChannelTopic(ctx, channel.ID, channelTopic)
+	if len(activeUserIDs) > 0 {
+		err = s.slackService.InviteUsersToChannel(ctx, channel.ID, activeUserIDs...)
 		if err != nil {
-			slog.WarnContext(ctx, "Failed to set channel topic (non-fatal)", "channelID", channel.ID, "error", err)
+			return abandon(fmt.Errorf("failed to invite users to incident channel: %w", err))
 		}
+	}
 
-		break
+	incident.ChannelID = channel.ID
+	incident.ChannelName = channel.Name
+	err = s.store.UpdateIncident(ctx, incident)
+	if err != nil {
+		return abandon(fmt.Errorf("failed to store incident channel: %w", err))
+	}
+	if confidential {
+		s.audit(ctx, incident, AuditPrivateChannel, createdBy, true, "#"+channel.Name)
+	}
+	s.recordChanges(ctx, &Incident{}, incident, createdBy)
+	s.webhooks.Publish(ctx, WebhookPayload{Event: WebhookIncidentCreated, Incident: incident})
+
+	err = s.slackService.SetChannelTopic(ctx, channel.ID, incidentTopic(incident))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to set channel topic (non-fatal)", "channelID", channel.ID, "error", err)
+	}
+	s.postBroadcast(ctx, incident.ID)
+	for _, roleID := range []string{RoleCommander, RoleComms} {
+		if userID := incident.RoleHolder(roleID); userID != "" {
+			role, _ := config.Taxonomy.Role(roleID)
+			s.sendRoleChecklist(ctx, incident, role, userID)
+		}
 	}
 
 	return channel, nil
 }
 
-// Helper function (can be defined at package level)
-func appendIfMissing(slice []string, i string) []string {
-	for _, ele := range slice {
-		if ele == i {
-			return slice
+// createNamedChannel creates the channel for a new incident. Each attempt
+// takes the next number of the global channel sequence, so a taken name is
+// only retried if the template uses {seq}.
+func (s *IncidentService) createNamedChannel(ctx context.Context, config *Config, incident *Incident) (*slack.Channel, error) {
+	template := config.ChannelTemplate
+	if template == "" {
+		template = defaultChannelTemplate
+	}
+	data := channelNameData{
+		Date:        time.Now(),
+		Severity:    string(incident.Severity),
+		Description: incident.Description,
+		ID:          incident.ID,
+	}
+	if level, ok := config.Taxonomy.Severity(incident.Severity); ok {
+		data.Severity = level.Label
+	}
+
+	var name string
+	for attempt := 0; attempt < maxChannelNameAttempts; attempt++ {
+		if channelTemplateUsesSeq(template) {
+			seq, err := s.store.NextSequence(ctx, "channel")
+			if err != nil {
+				return nil, err
+			}
+			data.Seq = seq
+		}
+		name = renderChannelName(config.ChannelPrefix, template, data)
+
+		channel, err := s.slackService.CreateChannel(ctx, name, incident.Confidential)
+		if err == nil {
+			return channel, nil
+		}
+		if !strings.Contains(err.Error(), "name_taken") {
+			return nil, fmt.Errorf("failed to create incident channel: %w", err)
+		}
+		slog.InfoContext(ctx, "Incident channel name taken", "name", name, "incidentID", incident.ID)
+		if !channelTemplateUsesSeq(template) {
+			break
 		}
 	}
-	return append(slice, i)
+	return nil, fmt.Errorf("%w: last tried #%s", ErrChannelNamesExhausted, name)
 }
 
-func (s *IncidentService) CreateTimeline(ctx context.Context, channelID, userName string, severity Severity, status Status) error {
-	headerText := slack.NewTextBlockObject("mrkdwn", "*Incident Timeline*", false, false)
-	headerSection := slack.NewSectionBlock(headerText, nil, nil)
+// IncidentDeclaration describes a new incident.
+type IncidentDeclaration struct {
+	Description string
+	Severity    Severity
+	Status      Status
+	CommanderID string
+	CommsRepID  string
+	// Members are invited to the incident channel along with the commander
+	// and comms rep.
+	Members []string
+	// DeclaredBy is the Slack user declaring the incident, if any.
+	DeclaredBy string
+	// Confidential incidents get a private channel, see Incident.
+	Confidential bool
+}
 
-	timelineText := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("%s - Incident created by <@%s>. Severity: %s Status: %s",
-		time.Now().UTC().Format("2006-01-02 15:04:05"), userName, severity, status), false, false)
-	timelineSection := slack.NewSectionBlock(timelineText, nil, nil)
+// DeclareIncident creates the incident channel and sets it up with a pinned
+// timeline and action item list, adding a postmortem action item where the
+// severity calls for one.
+func (s *IncidentService) DeclareIncident(ctx context.Context, d IncidentDeclaration) (*Incident, error) {
+	members := d.Members
+	if d.DeclaredBy != "" {
+		members = appendIfMissing(members, d.DeclaredBy)
+	}
 
-	blocks := []slack.Block{
-		headerSection,
-		timelineSection,
+	channel, err := s.CreateIncidentChannel(ctx, d.DeclaredBy, d.Description, d.Severity, d.Status, d.CommanderID, d.CommsRepID, d.Confidential, members...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create incident channel: %w", err)
 	}
 
-	timestamp, err := s.slackService.PostMessage(ctx, channelID, blocks)
+	err = s.CreateTimeline(ctx, channel.ID, d.DeclaredBy, d.Severity, d.Status)
 	if err != nil {
-		return fmt.Errorf("failed to create timeline: %w", err)
+		return nil, fmt.Errorf("failed to create timeline: %w", err)
 	}
 
-	err = s.slackService.AddPin(ctx, channelID, timestamp)
+	err = s.CreateActionItems(ctx, channel.ID)
 	if err != nil {
-		return fmt.Errorf("failed to pin timeline message: %w", err)
+		return nil, fmt.Errorf("failed to create action items: %w", err)
 	}
 
-	return nil
+	err = s.withIncident(ctx, channel.ID, func(incident *Incident) error {
+		s.applySeverityPolicies(ctx, incident, d.DeclaredBy, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.GetIncidentByChannel(ctx, channel.ID)
 }
 
-func (s *IncidentService) AddTimelineItem(ctx context.Context, channelID, userName, message string) error {
-	timelineItem, err := s.getTimelineMessage(ctx, channelID)
-	if err != nil {
-		return fmt.Errorf("failed to get timeline message: %w", err)
+// applySeverityPolicies runs the policies of the incident's severity that
+// the previous severity didn't already trigger. previous is empty for a new
+// incident. The caller must hold the incident lock.
+func (s *IncidentService) applySeverityPolicies(ctx context.Context, incident *Incident, userID string, previous Severity) {
+	taxonomy := s.config().Taxonomy
+	level, ok := taxonomy.Severity(incident.Severity)
+	if !ok {
+		return
 	}
+	before, _ := taxonomy.Severity(previous)
 
-	if timelineItem == nil {
-		return fmt.Errorf("timeline message not found")
+	if level.RequiresPostmortem && !before.RequiresPostmortem {
+		if err := s.addActionItem(ctx, incident, userID, incident.CommanderID, "Create incident postmortem"); err != nil {
+			slog.WarnContext(ctx, "Failed to add postmortem action item", "error", err)
+		}
 	}
 
-	additionalText := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("%s - %s by <@%s>",
-		time.Now().UTC().Format("2006-01-02 15:04:05"), message, userName), false, false)
-	additionalSection := slack.NewSectionBlock(additionalText, nil, nil)
+	if level.PageOnCall && !before.PageOnCall {
+		text := fmt.Sprintf("%s :rotating_light: *%s* incident: %s", taxonomy.OnCall, level.Label, incident.Description)
+		_, _, err := s.slackService.PostAttachment(ctx, incident.ChannelID, level.Color, []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to page on-call", "incidentID", incident.ID, "error", err)
+			return
+		}
+		if err := s.addTimelineItem(ctx, incident, userID, fmt.Sprintf("Paged on-call (%s).", taxonomy.OnCall)); err != nil {
+			slog.WarnContext(ctx, "Failed to add page to timeline", "incidentID", incident.ID, "error", err)
+		}
+	}
+}
 
-	updatedBlocks := append(timelineItem.Message.Blocks.BlockSet, additionalSection)
+// incidentTopic renders the channel topic for an incident. The topic is for
+// humans only; the store is the source of truth.
+func incidentTopic(incident *Incident) string {
+	topicParts := []string{fmt.Sprintf("%s incident: %s", incident.Severity, incident.Description)}
+	if incident.Status == StatusResolved {
+		topicParts[0] = fmt.Sprintf("Resolved: %s", incident.Description)
+	}
+	if incident.CommanderID != "" {
+		topicParts = append(topicParts, fmt.Sprintf("Commander: <@%s>", incident.CommanderID))
+	}
+	if incident.CommsRepID != "" {
+		topicParts = append(topicParts, fmt.Sprintf("Comms: <@%s>", incident.CommsRepID))
+	}
+	return strings.Join(topicParts, " | ")
+}
 
-	err = s.slackService.UpdateMessage(ctx, channelID, timelineItem.Message.Timestamp, updatedBlocks)
+// parseIncidentTopic recovers what it can from a topic written by
+// incidentTopic, assuming status for open incidents. It is only used for
+// channels created before incidents were stored.
+func parseIncidentTopic(topic string, status Status) (*Incident, bool) {
+	incident := &Incident{Status: status}
+
+	coreTopic := topic
+	if idx := strings.Index(coreTopic, " | Commander: <@"); idx != -1 {
+		start := idx + len(" | Commander: <@")
+		if end := strings.Index(coreTopic[start:], ">"); end != -1 {
+			incident.CommanderID = coreTopic[start : start+end]
+		}
+	}
+	if idx := strings.Index(coreTopic, " | Comms: <@"); idx != -1 {
+		start := idx + len(" | Comms: <@")
+		if end := strings.Index(coreTopic[start:], ">"); end != -1 {
+			incident.CommsRepID = coreTopic[start : start+end]
+		}
+	}
+	if idx := strings.Index(coreTopic, " | "); idx != -1 {
+		coreTopic = coreTopic[:idx]
+	}
+
+	if parts := strings.SplitN(coreTopic, " incident: ", 2); len(parts) == 2 {
+		incident.Severity = Severity(parts[0])
+		incident.Description = parts[1]
+		return incident, true
+	}
+	if strings.HasPrefix(coreTopic, "Resolved: ") {
+		incident.Status = StatusResolved
+		incident.Description = strings.TrimPrefix(coreTopic, "Resolved: ")
+		return incident, true
+	}
+	return nil, false
+}
+
+// incidentForChannel loads the incident for a channel. Channels created before
+// the store existed are adopted from their topic on first use.
+func (s *IncidentService) incidentForChannel(ctx context.Context, channelID string) (*Incident, error) {
+	incident, err := s.store.GetIncidentByChannel(ctx, channelID)
+	if !errors.Is(err, ErrIncidentNotFound) {
+		return incident, err
+	}
+
+	topic, topicErr := s.slackService.GetChannelTopic(ctx, channelID)
+	if topicErr != nil {
+		return nil, fmt.Errorf("failed to get channel topic: %w", topicErr)
+	}
+	incident, ok := parseIncidentTopic(topic, s.config().Taxonomy.InitialStatus())
+	if !ok {
+		return nil, ErrIncidentNotFound
+	}
+
+	incident.ChannelID = channelID
+	if incident.Status == StatusResolved {
+		now := time.Now().UTC()
+		incident.ResolvedAt = &now
+	}
+	if err := s.store.CreateIncident(ctx, incident); err != nil {
+		return nil, fmt.Errorf("failed to adopt incident from channel topic: %w", err)
+	}
+	slog.InfoContext(ctx, "Adopted incident from channel topic", "channelID", channelID, "incidentID", incident.ID)
+	return incident, nil
+}
+
+// withIncident runs fn on a fresh copy of the channel's incident while holding
+// the incident lock, so concurrent commands can't interleave their
+// read-modify-write cycles on the incident or its pinned messages.
+func (s *IncidentService) withIncident(ctx context.Context, channelID string, fn func(incident *Incident) error) error {
+	incident, err := s.incidentForChannel(ctx, channelID)
 	if err != nil {
-		return fmt.Errorf("failed to update timeline: %w", err)
+		return fmt.Errorf("failed to load incident: %w", err)
 	}
+	return s.withIncidentID(ctx, incident.ID, fn)
+}
 
-	return nil
+// withIncidentID is withIncident for an incident ID.
+func (s *IncidentService) withIncidentID(ctx context.Context, incidentID string, fn func(incident *Incident) error) error {
+	unlock := s.locks.Lock(incidentID)
+	defer unlock()
+
+	incident, err := s.store.GetIncident(ctx, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+	return fn(incident)
 }
 
-func (s *IncidentService) getTimelineMessage(ctx context.Context, channelID string) (*slack.Item, error) {
-	items, err := s.slackService.ListPins(ctx, channelID)
+// GetIncident returns the stored incident with the given ID.
+func (s *IncidentService) GetIncident(ctx context.Context, incidentID string) (*Incident, error) {
+	return s.store.GetIncident(ctx, incidentID)
+}
+
+// ListTimeline returns an incident's timeline, oldest first.
+func (s *IncidentService) ListTimeline(ctx context.Context, incidentID string) ([]TimelineItem, error) {
+	items, err := s.store.ListTimelineItems(ctx, incidentID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list timeline: %w", err)
 	}
+	return items, nil
+}
 
-	if len(items) == 0 {
-		slog.WarnContext(ctx, "No pins found in channel", "channelID", channelID)
-		return nil, nil
+// recordChanges adds the status, severity and role changes between before
+// and after to the incident's history. Callers have already stored the
+// change, and a missing history row only leaves a gap in the postmortem
+// draft, so failures are logged rather than returned.
+func (s *IncidentService) recordChanges(ctx context.Context, before, after *Incident, userID string) {
+	type fieldChange struct {
+		field    ChangeField
+		from, to string
 	}
+	fields := []fieldChange{
+		{ChangeStatus, string(before.Status), string(after.Status)},
+		{ChangeSeverity, string(before.Severity), string(after.Severity)},
+		{ChangeCommander, before.CommanderID, after.CommanderID},
+		{ChangeCommsRep, before.CommsRepID, after.CommsRepID},
+	}
+	roleIDs := make([]string, 0, len(before.Roles)+len(after.Roles))
+	for roleID := range before.Roles {
+		roleIDs = append(roleIDs, roleID)
+	}
+	for roleID := range after.Roles {
+		roleIDs = appendIfMissing(roleIDs, roleID)
+	}
+	slices.Sort(roleIDs)
+	for _, roleID := range roleIDs {
+		fields = append(fields, fieldChange{roleChangeField(roleID), before.Roles[roleID], after.Roles[roleID]})
+	}
+	for _, f := range fields {
+		if f.from == f.to {
+			continue
+		}
+		err := s.store.AddIncidentChange(ctx, &IncidentChange{IncidentID: after.ID, Field: f.field, From: f.from, To: f.to, User: userID})
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to record incident change", "incidentID", after.ID, "field", f.field, "error", err)
+		}
+	}
+}
 
-	for _, item := range items {
-		if strings.Contains(item.Message.Text, "*Incident Timeline*") {
-			return &item, nil
+// Helper function (can be defined at package level)
+func appendIfMissing(slice []string, i string) []string {
+	for _, ele := range slice {
+		if ele == i {
+			return slice
 		}
 	}
+	return append(slice, i)
+}
 
-	return nil, nil
+func (s *IncidentService) CreateTimeline(ctx context.Context, channelID, userID string, severity Severity, status Status) error {
+	return s.AddTimelineItem(ctx, channelID, userID, fmt.Sprintf("Incident created. Severity: %s Status: %s", severity, status))
 }
 
-func (s *IncidentService) CreateActionItems(ctx context.Context, channelID, userName string) error {
-	headerText := slack.NewTextBlockObject("mrkdwn", "*Action Items*", false, false)
-	headerSection := slack.NewSectionBlock(headerText, nil, nil)
+// AddTimelineItem records a timeline entry and re-renders the pinned timeline
+// message from the stored entries.
+func (s *IncidentService) AddTimelineItem(ctx context.Context, channelID, userID, message string) error {
+	return s.withIncident(ctx, channelID, func(incident *Incident) error {
+		return s.addTimelineItem(ctx, incident, userID, message)
+	})
+}
+
+// addTimelineItem is AddTimelineItem for callers that already hold the
+// incident lock.
+func (s *IncidentService) addTimelineItem(ctx context.Context, incident *Incident, userID, message string) error {
+	if incident.TimelineTS == "" {
+		err := s.importLegacyTimeline(ctx, incident)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to import legacy timeline message", "incidentID", incident.ID, "error", err)
+		}
+	}
 
-	blocks := []slack.Block{
-		headerSection,
+	item := &TimelineItem{
+		IncidentID: incident.ID,
+		User:       userID,
+		Message:    message,
 	}
+	if err := s.store.AddTimelineItem(ctx, item); err != nil {
+		return fmt.Errorf("failed to store timeline item: %w", err)
+	}
+	s.webhooks.Publish(ctx, WebhookPayload{Event: WebhookTimelineAdded, Incident: incident, TimelineItem: item})
+
+	return s.renderTimeline(ctx, incident)
+}
 
-	timestamp, err := s.slackService.PostMessage(ctx, channelID, blocks)
+// renderTimeline rewrites the pinned timeline message from the stored
+// entries. Callers must hold the incident lock.
+func (s *IncidentService) renderTimeline(ctx context.Context, incident *Incident) error {
+	items, err := s.store.ListTimelineItems(ctx, incident.ID)
 	if err != nil {
-		return fmt.Errorf("failed to create action items: %w", err)
+		return fmt.Errorf("failed to list timeline items: %w", err)
 	}
+	return s.syncPinnedMessage(ctx, incident, &incident.TimelineTS, timelineBlocks(items))
+}
 
-	err = s.slackService.AddPin(ctx, channelID, timestamp)
+// syncPinnedMessage replaces the content of one of the incident's pinned
+// messages, identified by *ts. If the message is gone it is posted and pinned
+// again and the new timestamp is stored. Callers must hold the incident lock.
+func (s *IncidentService) syncPinnedMessage(ctx context.Context, incident *Incident, ts *string, blocks []slack.Block) error {
+	if *ts != "" {
+		err := s.slackService.UpdateMessage(ctx, incident.ChannelID, *ts, blocks)
+		if err == nil {
+			return nil
+		}
+		slog.WarnContext(ctx, "Failed to update pinned message, posting a new one", "incidentID", incident.ID, "error", err)
+	}
+
+	timestamp, err := s.slackService.PostMessage(ctx, incident.ChannelID, blocks)
 	if err != nil {
-		return fmt.Errorf("failed to pin action items message: %w", err)
+		return fmt.Errorf("failed to post message: %w", err)
 	}
 
-	return nil
-}
+	err = s.slackService.AddPin(ctx, incident.ChannelID, timestamp)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to pin message", "incidentID", incident.ID, "error", err)
+	}
 
-func (s *IncidentService) AddActionItem(ctx context.Context, channelID, userName, description string) error {
-	actionItem, err := s.getActionItemMessage(ctx, channelID)
+	*ts = timestamp
+	err = s.store.UpdateIncident(ctx, incident)
 	if err != nil {
-		return fmt.Errorf("failed to get action items message: %w", err)
+		return fmt.Errorf("failed to store pinned message timestamp: %w", err)
 	}
+	return nil
+}
+
+const timelineTimeFormat = "2006-01-02 15:04:05"
 
-	if actionItem == nil {
-		return fmt.Errorf("action items message not found")
+// timelineBlocks renders timeline entries, packing several entries into each
+// section so long incidents stay within Slack's per-message block limit.
+func timelineBlocks(items []TimelineItem) []slack.Block {
+	const maxSectionText = 3000
+
+	headerText := slack.NewTextBlockObject("mrkdwn", "*Incident Timeline*", false, false)
+	blocks := []slack.Block{slack.NewSectionBlock(headerText, nil, nil)}
+
+	var section strings.Builder
+	flush := func() {
+		if section.Len() == 0 {
+			return
+		}
+		text := slack.NewTextBlockObject("mrkdwn", section.String(), false, false)
+		blocks = append(blocks, slack.NewSectionBlock(text, nil, nil))
+		section.Reset()
 	}
 
-	if strings.Contains(actionItem.Message.Text, description) {
-		return nil
+	for _, item := range items {
+		line := timelineLine(item)
+		if section.Len() > 0 && section.Len()+len(line)+1 > maxSectionText {
+			flush()
+		}
+		if section.Len() > 0 {
+			section.WriteString("\n")
+		}
+		section.WriteString(line)
 	}
+	flush()
 
-	additionalText := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("<@%s> - %s", userName, description), false, false)
-	additionalSection := slack.NewSectionBlock(additionalText, nil, nil)
+	return blocks
+}
 
-	updatedBlocks := append(actionItem.Message.Blocks.BlockSet, additionalSection)
+func timelineLine(item TimelineItem) string {
+	line := fmt.Sprintf("%s - %s", item.Timestamp.UTC().Format(timelineTimeFormat), item.Message)
+	if item.User != "" {
+		line += fmt.Sprintf(" by <@%s>", item.User)
+	}
+	return line
+}
 
-	err = s.slackService.UpdateMessage(ctx, channelID, actionItem.Message.Timestamp, updatedBlocks)
-	if err != nil {
-		return fmt.Errorf("failed to update action items: %w", err)
+// importLegacyTimeline copies the entries of a timeline message written before
+// timeline items were stored, so re-rendering doesn't drop them.
+func (s *IncidentService) importLegacyTimeline(ctx context.Context, incident *Incident) error {
+	pinned, err := s.getTimelineMessage(ctx, incident.ChannelID)
+	if err != nil || pinned == nil {
+		return err
 	}
 
-	return nil
+	for _, block := range pinned.Message.Blocks.BlockSet[min(1, len(pinned.Message.Blocks.BlockSet)):] {
+		section, ok := block.(*slack.SectionBlock)
+		if !ok || section.Text == nil {
+			continue
+		}
+		for _, line := range strings.Split(section.Text.Text, "\n") {
+			item := TimelineItem{IncidentID: incident.ID, Message: line}
+			if when, message, found := strings.Cut(line, " - "); found {
+				if ts, err := time.Parse(timelineTimeFormat, when); err == nil {
+					item.Timestamp = ts
+					item.Message = message
+				}
+			}
+			if err := s.store.AddTimelineItem(ctx, &item); err != nil {
+				return err
+			}
+		}
+	}
+
+	incident.TimelineTS = pinned.Message.Timestamp
+	return s.store.UpdateIncident(ctx, incident)
 }
 
-func (s *IncidentService) getActionItemMessage(ctx context.Context, channelID string) (*slack.Item, error) {
+func (s *IncidentService) getTimelineMessage(ctx context.Context, channelID string) (*slack.Item, error) {
 	items, err := s.slackService.ListPins(ctx, channelID)
 	if err != nil {
 		return nil, err
@@ -218,7 +583,7 @@ func (s *IncidentService) getActionItemMessage(ctx context.Context, channelID st
 	}
 
 	for _, item := range items {
-		if strings.Contains(item.Message.Text, "*Action Items*") {
+		if strings.Contains(item.Message.Text, "*Incident Timeline*") {
 			return &item, nil
 		}
 	}
@@ -234,27 +599,8 @@ func (s *IncidentService) CreateIncidentModal() slack.ModalViewRequest {
 	headerText := slack.NewTextBlockObject("mrkdwn", "Enter the below details for the incident channel.", false, false)
 	headerSection := slack.NewSectionBlock(headerText, nil, nil)
 
-	statusText := slack.NewTextBlockObject("plain_text", "status", false, false)
-	statusPlaceholder := slack.NewTextBlockObject("plain_text", "Current Status...", false, false)
-	statusOptions := []*slack.OptionBlockObject{
-		slack.NewOptionBlockObject("1", slack.NewTextBlockObject("plain_text", "Investigating", false, false), slack.NewTextBlockObject("plain_text", "Incident is under investigation.", false, false)),
-		slack.NewOptionBlockObject("2", slack.NewTextBlockObject("plain_text", "Fixing", false, false), slack.NewTextBlockObject("plain_text", "A fix is being implemented.", false, false)),
-		slack.NewOptionBlockObject("3", slack.NewTextBlockObject("plain_text", "Monitoring", false, false), slack.NewTextBlockObject("plain_text", "Fix implemented, monitoring for stability.", false, false)),
-		slack.NewOptionBlockObject("4", slack.NewTextBlockObject("plain_text", "Resolved", false, false), slack.NewTextBlockObject("plain_text", "The incident has been resolved.", false, false)),
-	}
-	statusSelection := slack.NewOptionsSelectBlockElement("static_select", statusPlaceholder, "status", statusOptions...)
-	status := slack.NewInputBlock("status", statusText, nil, statusSelection)
-
-	severityText := slack.NewTextBlockObject("plain_text", "severity", false, false)
-	severityPlaceholder := slack.NewTextBlockObject("plain_text", "Select Severity...", false, false)
-	severityOptions := []*slack.OptionBlockObject{
-		slack.NewOptionBlockObject("1", slack.NewTextBlockObject("plain_text", "SEV-0", false, false), slack.NewTextBlockObject("plain_text", "Critical: High impact, affects many users.", false, false)),
-		slack.NewOptionBlockObject("2", slack.NewTextBlockObject("plain_text", "SEV-1", false, false), slack.NewTextBlockObject("plain_text", "Major: Significant impact, affects some users.", false, false)),
-		slack.NewOptionBlockObject("3", slack.NewTextBlockObject("plain_text", "SEV-2", false, false), slack.NewTextBlockObject("plain_text", "Moderate: Non-critical impact or user inconvenience.", false, false)),
-		slack.NewOptionBlockObject("4", slack.NewTextBlockObject("plain_text", "SEV-3", false, false), slack.NewTextBlockObject("plain_text", "Minor: Low priority, no immediate attention needed.", false, false)),
-	}
-	severitySelection := slack.NewOptionsSelectBlockElement("static_select", severityPlaceholder, "incident_severity", severityOptions...)
-	severity := slack.NewInputBlock("incident_severity", severityText, nil, severitySelection)
+	status := s.statusInput()
+	severity := s.severityInput()
 
 	descriptionText := slack.NewTextBlockObject("plain_text", "description", false, false)
 	descriptionHint := slack.NewTextBlockObject("plain_text", "Example: Increased latency in Carehub", false, false)
@@ -291,6 +637,15 @@ func (s *IncidentService) CreateIncidentModal() slack.ModalViewRequest {
 	members := slack.NewInputBlock("incident_members", membersText, membersHint, membersSelection)
 	members.Optional = true
 
+	// Confidential toggle
+	confidentialText := slack.NewTextBlockObject("plain_text", "Access", false, false)
+	confidentialOption := slack.NewOptionBlockObject("confidential",
+		slack.NewTextBlockObject("plain_text", "Confidential", false, false),
+		slack.NewTextBlockObject("plain_text", "For security and HR incidents: use a private channel, don't announce it and hide it from other people's incident lists.", false, false))
+	confidentialElement := slack.NewCheckboxGroupsBlockElement("confidential", confidentialOption)
+	confidential := slack.NewInputBlock("confidential", confidentialText, nil, confidentialElement)
+	confidential.Optional = true
+
 	blocks := slack.Blocks{
 		BlockSet: []slack.Block{
 			headerSection,
@@ -300,6 +655,7 @@ func (s *IncidentService) CreateIncidentModal() slack.ModalViewRequest {
 			commanderInput,
 			commsRepInput,
 			members,
+			confidential,
 		},
 	}
 
@@ -314,92 +670,72 @@ func (s *IncidentService) CreateIncidentModal() slack.ModalViewRequest {
 	return modalRequest
 }
 
-// UpdateIncidentModal now needs channelID to pre-fill commander/comms rep
-func (s *IncidentService) UpdateIncidentModal(ctx context.Context, channelID string) slack.ModalViewRequest {
-	titleText := slack.NewTextBlockObject("plain_text", "Update an Incident", false, false)
-	closeText := slack.NewTextBlockObject("plain_text", "Cancel", false, false)
-	submitText := slack.NewTextBlockObject("plain_text", "Update Incident", false, false)
-
-	headerText := slack.NewTextBlockObject("mrkdwn", "Enter the below details for the incident update.", false, false)
-	headerSection := slack.NewSectionBlock(headerText, nil, nil)
+// statusInput is the status select for the create and update modals. Option
+// values are status IDs.
+func (s *IncidentService) statusInput() *slack.InputBlock {
+	var options []*slack.OptionBlockObject
+	for _, level := range s.config().Taxonomy.Statuses {
+		options = append(options, slack.NewOptionBlockObject(string(level.ID), slack.NewTextBlockObject("plain_text", level.Label, false, false), optionDescription(level.Description)))
+	}
 
-	// Status and Severity fields (as before)
 	statusText := slack.NewTextBlockObject("plain_text", "status", false, false)
 	statusPlaceholder := slack.NewTextBlockObject("plain_text", "Current Status...", false, false)
-	statusOptions := []*slack.OptionBlockObject{
-		slack.NewOptionBlockObject("1", slack.NewTextBlockObject("plain_text", "Investigating", false, false), slack.NewTextBlockObject("plain_text", "Incident is under investigation.", false, false)),
-		slack.NewOptionBlockObject("2", slack.NewTextBlockObject("plain_text", "Fixing", false, false), slack.NewTextBlockObject("plain_text", "A fix is being implemented.", false, false)),
-		slack.NewOptionBlockObject("3", slack.NewTextBlockObject("plain_text", "Monitoring", false, false), slack.NewTextBlockObject("plain_text", "Fix implemented, monitoring for stability.", false, false)),
-		slack.NewOptionBlockObject("4", slack.NewTextBlockObject("plain_text", "Resolved", false, false), slack.NewTextBlockObject("plain_text", "The incident has been resolved.", false, false)),
+	statusSelection := slack.NewOptionsSelectBlockElement("static_select", statusPlaceholder, "status", options...)
+	return slack.NewInputBlock("status", statusText, nil, statusSelection)
+}
+
+// severityInput is the severity select for the create and update modals.
+// Option values are severity IDs.
+func (s *IncidentService) severityInput() *slack.InputBlock {
+	var options []*slack.OptionBlockObject
+	for _, level := range s.config().Taxonomy.Severities {
+		options = append(options, slack.NewOptionBlockObject(string(level.ID), slack.NewTextBlockObject("plain_text", level.Label, false, false), optionDescription(level.Description)))
 	}
-	statusSelection := slack.NewOptionsSelectBlockElement("static_select", statusPlaceholder, "status", statusOptions...)
-	status := slack.NewInputBlock("status", statusText, nil, statusSelection)
 
 	severityText := slack.NewTextBlockObject("plain_text", "severity", false, false)
 	severityPlaceholder := slack.NewTextBlockObject("plain_text", "Select Severity...", false, false)
-	severityOptions := []*slack.OptionBlockObject{
-		slack.NewOptionBlockObject("1", slack.NewTextBlockObject("plain_text", "SEV-0", false, false), slack.NewTextBlockObject("plain_text", "Critical: High impact, affects many users.", false, false)),
-		slack.NewOptionBlockObject("2", slack.NewTextBlockObject("plain_text", "SEV-1", false, false), slack.NewTextBlockObject("plain_text", "Major: Significant impact, affects some users.", false, false)),
-		slack.NewOptionBlockObject("3", slack.NewTextBlockObject("plain_text", "SEV-2", false, false), slack.NewTextBlockObject("plain_text", "Moderate: Non-critical impact or user inconvenience.", false, false)),
-		slack.NewOptionBlockObject("4", slack.NewTextBlockObject("plain_text", "SEV-3", false, false), slack.NewTextBlockObject("plain_text", "Minor: Low priority, no immediate attention needed.", false, false)),
-	}
-	severitySelection := slack.NewOptionsSelectBlockElement("static_select", severityPlaceholder, "incident_severity", severityOptions...)
-	severity := slack.NewInputBlock("incident_severity", severityText, nil, severitySelection)
-
-	// Fetch current topic to extract commander and comms rep for pre-filling
-	currentCommanderID, currentCommsRepID := "", ""
-	currentTopic, err := s.slackService.GetChannelTopic(ctx, channelID)
-	if err == nil {
-		if idx := strings.Index(currentTopic, " | Commander: <@"); idx != -1 {
-			start := idx + len(" | Commander: <@")
-			end := strings.Index(currentTopic[start:], ">")
-			if end != -1 {
-				currentCommanderID = currentTopic[start : start+end]
-			}
-		}
-		if idx := strings.Index(currentTopic, " | Comms: <@"); idx != -1 {
-			start := idx + len(" | Comms: <@")
-			end := strings.Index(currentTopic[start:], ">")
-			if end != -1 {
-				currentCommsRepID = currentTopic[start : start+end]
-			}
-		}
-	} else {
-		slog.WarnContext(ctx, "Could not get current topic for UpdateIncidentModal prefill", "channelID", channelID, "error", err)
-	}
+	severitySelection := slack.NewOptionsSelectBlockElement("static_select", severityPlaceholder, "incident_severity", options...)
+	return slack.NewInputBlock("incident_severity", severityText, nil, severitySelection)
+}
 
-	// Incident Commander field (optional)
-	commanderText := slack.NewTextBlockObject("plain_text", "Incident Commander (Optional - select one)", false, false)
-	commanderPlaceholder := slack.NewTextBlockObject("plain_text", "Select Incident Commander", false, false)
-	commanderElement := &slack.SelectBlockElement{
-		Type: "users_select", Placeholder: commanderPlaceholder, ActionID: "incident_commander",
+// optionDescription returns nil for an empty description, which Slack
+// rejects, and truncates it to Slack's 75 character limit.
+func optionDescription(description string) *slack.TextBlockObject {
+	if description == "" {
+		return nil
 	}
-	if currentCommanderID != "" {
-		commanderElement.InitialUser = currentCommanderID
+	if runes := []rune(description); len(runes) > 75 {
+		description = string(runes[:74]) + "…"
 	}
-	commanderInput := slack.NewInputBlock("incident_commander", commanderText, nil, commanderElement)
-	commanderInput.Optional = true
+	return slack.NewTextBlockObject("plain_text", description, false, false)
+}
 
-	// Comms Representative field (optional)
-	commsRepText := slack.NewTextBlockObject("plain_text", "Comms Representative (Optional - select one)", false, false)
-	commsRepPlaceholder := slack.NewTextBlockObject("plain_text", "Select Comms Representative", false, false)
-	commsRepElement := &slack.SelectBlockElement{
-		Type: "users_select", Placeholder: commsRepPlaceholder, ActionID: "comms_representative",
-	}
-	if currentCommsRepID != "" {
-		commsRepElement.InitialUser = currentCommsRepID
+// UpdateIncidentModal needs channelID to pre-fill the role pickers, one for
+// each role in the taxonomy.
+func (s *IncidentService) UpdateIncidentModal(ctx context.Context, channelID string) slack.ModalViewRequest {
+	titleText := slack.NewTextBlockObject("plain_text", "Update an Incident", false, false)
+	closeText := slack.NewTextBlockObject("plain_text", "Cancel", false, false)
+	submitText := slack.NewTextBlockObject("plain_text", "Update Incident", false, false)
+
+	headerText := slack.NewTextBlockObject("mrkdwn", "Enter the below details for the incident update.", false, false)
+	headerSection := slack.NewSectionBlock(headerText, nil, nil)
+
+	status := s.statusInput()
+	severity := s.severityInput()
+
+	// Fetch the current incident to pre-fill the role pickers
+	incident, err := s.incidentForChannel(ctx, channelID)
+	if err != nil {
+		slog.WarnContext(ctx, "Could not load incident for UpdateIncidentModal prefill", "channelID", channelID, "error", err)
+		incident = nil
 	}
-	commsRepInput := slack.NewInputBlock("comms_representative", commsRepText, nil, commsRepElement)
-	commsRepInput.Optional = true
 
 	blocks := slack.Blocks{
-		BlockSet: []slack.Block{
+		BlockSet: append([]slack.Block{
 			headerSection,
 			severity,
 			status,
-			commanderInput,
-			commsRepInput,
-		},
+		}, s.roleInputs(incident)...),
 	}
 
 	var modalRequest slack.ModalViewRequest
@@ -421,10 +757,43 @@ func (s *IncidentService) HelpMessage() *slack.Blocks {
 	createText := slack.NewTextBlockObject("mrkdwn", "*🆕 Use `/incident create` (or `c`)*. I will ask you for some details, and create a new incident.", false, false)
 	createSection := slack.NewSectionBlock(createText, nil, nil)
 
-	updateText := slack.NewTextBlockObject("mrkdwn", "*🔄 Use `/incident update` (or `u`)*. Change the status or severity of an incident.", false, false)
+	updateText := slack.NewTextBlockObject("mrkdwn", "*🔄 Use `/incident update` (or `u`)*. Change the status, severity or roles of an incident.", false, false)
 	updateSection := slack.NewSectionBlock(updateText, nil, nil)
 
-	actionItemText := slack.NewTextBlockObject("mrkdwn", "*🧹 Use `/incident action-item <description>` (or `ai <description>`)*. Adds an action item to the incident.", false, false)
+	taxonomy := s.config().Taxonomy
+	levelLines := []string{"*Severities*"}
+	for _, level := range taxonomy.Severities {
+		line := fmt.Sprintf("• *%s*", level.Label)
+		if level.Description != "" {
+			line += " " + level.Description
+		}
+		if level.RequiresPostmortem {
+			line += " _Postmortem required._"
+		}
+		if level.PageOnCall {
+			line += " _Pages on-call._"
+		}
+		levelLines = append(levelLines, line)
+	}
+	statusLabels := make([]string, 0, len(taxonomy.Statuses))
+	for _, level := range taxonomy.Statuses {
+		statusLabels = append(statusLabels, level.Label)
+	}
+	levelLines = append(levelLines, "*Statuses:* "+strings.Join(statusLabels, " → "))
+	roleLabels := make([]string, 0, len(taxonomy.Roles))
+	for _, role := range taxonomy.Roles {
+		roleLabels = append(roleLabels, fmt.Sprintf("%s (`%s`)", role.Label, role.ID))
+	}
+	levelLines = append(levelLines, "*Roles:* "+strings.Join(roleLabels, ", "))
+	levelsContext := slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", strings.Join(levelLines, "\n"), false, false))
+
+	roleText := slack.NewTextBlockObject("mrkdwn", "*👥 Use `/incident role assign <role> @user`*. Gives someone a role and sends them its checklist. `role unassign <role>` clears it and `role list` shows who has each.", false, false)
+	roleSection := slack.NewSectionBlock(roleText, nil, nil)
+
+	handoffText := slack.NewTextBlockObject("mrkdwn", "*🤝 Use `/incident handoff @user`*. Sends them a briefing on the incident and makes them commander once they accept.", false, false)
+	handoffSection := slack.NewSectionBlock(handoffText, nil, nil)
+
+	actionItemText := slack.NewTextBlockObject("mrkdwn", "*🧹 Use `/incident action-item [@user] <description>` (or `ai [@user] <description>`)*. Adds an action item to the incident, assigned to the user if you mention one. Tick its checkbox in the pinned message, or use `ai done <n>`, to mark it complete, and `ai list` to see them all.", false, false)
 	actionItemSection := slack.NewSectionBlock(actionItemText, nil, nil)
 
 	timelineText := slack.NewTextBlockObject("mrkdwn", "*⏰ Use `/incident timeline <message>` (or `t <message>`)*. Adds an event to the incident timeline.", false, false)
@@ -433,18 +802,41 @@ func (s *IncidentService) HelpMessage() *slack.Blocks {
 	resolveText := slack.NewTextBlockObject("mrkdwn", "*✅ Use `/incident resolve [optional message]` (or `r [optional message]`)*. Marks the incident as resolved and updates the channel topic.", false, false)
 	resolveSection := slack.NewSectionBlock(resolveText, nil, nil)
 
+	reopenText := slack.NewTextBlockObject("mrkdwn", "*↩️ Use `/incident reopen [INC-n] [reason]`*. Moves a resolved incident back to its previous status, unarchiving its channel if needed.", false, false)
+	reopenSection := slack.NewSectionBlock(reopenText, nil, nil)
+
+	postmortemText := slack.NewTextBlockObject("mrkdwn", "*📝 Use `/incident postmortem` (or `pm`)*. Shares a Markdown postmortem draft built from the incident's roles, impact window, severity changes, timeline and action items.", false, false)
+	postmortemSection := slack.NewSectionBlock(postmortemText, nil, nil)
+
+	listText := slack.NewTextBlockObject("mrkdwn", "*📋 Use `/incident list [all|open|resolved] [sev]` (or `ls`)*. Shows open incidents, or the ones matching the filter.", false, false)
+	listSection := slack.NewSectionBlock(listText, nil, nil)
+
+	statsText := slack.NewTextBlockObject("mrkdwn", "*📊 Use `/incident stats [7d|12w|all]`*. Shows incident counts, time to acknowledge and resolve, time in each status and reopen rate, for the last 30 days by default.", false, false)
+	statsSection := slack.NewSectionBlock(statsText, nil, nil)
+
 	helpText := slack.NewTextBlockObject("mrkdwn", "*🤖 Use `/incident help` (or `h`)*. Show this menu again.", false, false)
 	helpSection := slack.NewSectionBlock(helpText, nil, nil)
 
+	mentionText := slack.NewTextBlockObject("mrkdwn", "You can also mention me (`@hal timeline deploy rolled back`) or send me a DM with any command except `create` and `update`.", false, false)
+	mentionContext := slack.NewContextBlock("", mentionText)
+
 	blocks := slack.Blocks{
 		BlockSet: []slack.Block{
 			introSection,
 			createSection,
 			updateSection,
+			levelsContext,
+			roleSection,
+			handoffSection,
 			actionItemSection,
 			timelineSection,
 			resolveSection,
+			reopenSection,
+			postmortemSection,
+			listSection,
+			statsSection,
 			helpSection,
+			mentionContext,
 		},
 	}
 
@@ -464,74 +856,127 @@ func (s *IncidentService) UpdateIncident(ctx context.Context, triggerID, channel
 	return s.slackService.OpenView(ctx, triggerID, modal)
 }
 
-func (s *IncidentService) ResolveIncident(ctx context.Context, channelID, userID, userName, resolutionMessage string) error {
-	// Add to timeline
-	timelineMsg := fmt.Sprintf("Incident resolved by <@%s>.", userName)
-	if resolutionMessage != "" {
-		timelineMsg = fmt.Sprintf("Incident resolved by <@%s>. Resolution: %s", userName, resolutionMessage)
-	}
-	err := s.AddTimelineItem(ctx, channelID, userID, timelineMsg)
-	if err != nil {
-		// Log the error but don't block topic update if timeline fails for some reason
-		slog.ErrorContext(ctx, "Failed to add resolved item to timeline", "channelID", channelID, "error", err)
-	}
-
-	// Update channel topic
-	currentTopic, err := s.slackService.GetChannelTopic(ctx, channelID)
-	if err != nil {
-		slog.ErrorContext(ctx, "Failed to get channel topic for resolving incident", "channelID", channelID, "error", err)
-		// Proceed without updating topic if fetching fails
-	} else {
-		originalDescription := ""
-		commanderPart := ""
-		commsPart := ""
-
-		// Topic format: "<SEV-X/Resolved> incident: <description> [| Commander: <@...>] [| Comms: <@...>]"
-		// Extract original description first
-		coreTopic := currentTopic
-		if idx := strings.Index(coreTopic, " | Commander:"); idx != -1 {
-			coreTopic = coreTopic[:idx]
-		}
-		if idx := strings.Index(coreTopic, " | Comms:"); idx != -1 {
-			coreTopic = coreTopic[:idx]
-		}
-
-		if strings.Contains(coreTopic, " incident: ") {
-			parts := strings.SplitN(coreTopic, " incident: ", 2)
-			if len(parts) == 2 {
-				originalDescription = parts[1]
+// UpdateIncidentDetails applies an update modal submission: it stores the new
+// status, severity and roles, refreshes the topic, invites new role holders
+// and records the change on the timeline. roles maps role IDs to their new
+// holders, "" to unassign; roles it leaves out are unchanged.
+func (s *IncidentService) UpdateIncidentDetails(ctx context.Context, channelID, userID string, status Status, severity Severity, roles map[string]string) error {
+	return s.withIncident(ctx, channelID, func(incident *Incident) error {
+		before := *incident
+		before.Roles = maps.Clone(incident.Roles)
+		oldSeverity := incident.Severity
+		oldTopic := incidentTopic(incident)
+		wasResolved := incident.Status == StatusResolved
+
+		if !wasResolved && status == StatusResolved {
+			incident.PreviousStatus = incident.Status
+			now := time.Now().UTC()
+			incident.ResolvedAt = &now
+		}
+		if wasResolved && status != StatusResolved {
+			// The channel may have been archived since, and everything
+			// below posts to it.
+			if err := s.unarchiveIncidentChannel(ctx, incident); err != nil {
+				return err
 			}
-		} else if strings.HasPrefix(coreTopic, "Resolved: ") { // If already resolved, description is after "Resolved: "
-			originalDescription = strings.TrimPrefix(coreTopic, "Resolved: ")
-		} else if coreTopic != "" { // Fallback for other formats
-			originalDescription = coreTopic
-			slog.WarnContext(ctx, "Could not parse original description from topic for resolve, using full core topic", "channelID", channelID, "coreTopic", coreTopic)
-		} else {
-			originalDescription = "Description unavailable"
-			slog.WarnContext(ctx, "Original topic was empty or unparsable for resolve", "channelID", channelID)
-		}
-
-		// Preserve commander and comms if they exist in the original topic
-		if idx := strings.Index(currentTopic, " | Commander: "); idx != -1 {
-			tempPart := currentTopic[idx+len(" | Commander: "):]
-			if commsIdx := strings.Index(tempPart, " | Comms: "); commsIdx != -1 {
-				commanderPart = fmt.Sprintf(" | Commander: %s", tempPart[:commsIdx])
-			} else {
-				commanderPart = fmt.Sprintf(" | Commander: %s", tempPart)
+			markReopened(incident)
+		}
+		incident.Status = status
+		incident.Severity = severity
+		roleChanges, err := s.setRoles(incident, roles)
+		if err != nil {
+			return err
+		}
+
+		err = s.store.UpdateIncident(ctx, incident)
+		if err != nil {
+			return fmt.Errorf("failed to store incident update: %w", err)
+		}
+		s.recordChanges(ctx, &before, incident, userID)
+		event := WebhookIncidentUpdated
+		if !wasResolved && status == StatusResolved {
+			event = WebhookIncidentResolved
+		}
+		s.webhooks.Publish(ctx, WebhookPayload{Event: event, Incident: incident})
+
+		if newTopic := incidentTopic(incident); newTopic != oldTopic { // Only update if there's a change
+			err = s.slackService.SetChannelTopic(ctx, channelID, newTopic)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to update channel topic", "channelID", channelID, "newTopic", newTopic, "error", err)
 			}
 		}
-		if idx := strings.Index(currentTopic, " | Comms: "); idx != -1 {
-			commsPart = fmt.Sprintf(" | Comms: %s", currentTopic[idx+len(" | Comms: "):])
+		s.announceRoleChanges(ctx, incident, roleChanges)
+		s.updateBroadcast(ctx, incident)
+
+		// Add timeline item for the update (status, severity, and potentially roles)
+		updateMessages := []string{fmt.Sprintf("Incident updated. Status: %s, Severity: %s", status, severity)}
+		if wasResolved && status != StatusResolved {
+			updateMessages[0] = fmt.Sprintf("Incident reopened. Status: %s, Severity: %s", status, severity)
+		}
+		for _, change := range roleChanges {
+			updateMessages = append(updateMessages, change.message())
+		}
+
+		if severity != oldSeverity {
+			s.applySeverityPolicies(ctx, incident, userID, oldSeverity)
+		}
+
+		err = s.addTimelineItem(ctx, incident, userID, strings.Join(updateMessages, " "))
+		if err != nil {
+			return fmt.Errorf("failed to add timeline item: %w", err)
 		}
 
-		newTopic := fmt.Sprintf("Resolved: %s%s%s", originalDescription, commanderPart, commsPart)
+		return nil
+	})
+}
+
+func (s *IncidentService) ResolveIncident(ctx context.Context, channelID, userID, resolutionMessage string) error {
+	err := s.withIncident(ctx, channelID, func(incident *Incident) error {
+		// Add to timeline
+		timelineMsg := "Incident resolved."
+		if resolutionMessage != "" {
+			timelineMsg = fmt.Sprintf("Incident resolved. Resolution: %s", resolutionMessage)
+		}
+		err := s.addTimelineItem(ctx, incident, userID, timelineMsg)
+		if err != nil {
+			// Log the error but don't block topic update if timeline fails for some reason
+			slog.ErrorContext(ctx, "Failed to add resolved item to timeline", "channelID", channelID, "error", err)
+		}
+
+		before := *incident
+		wasResolved := incident.Status == StatusResolved
+		if !wasResolved {
+			incident.PreviousStatus = incident.Status
+			now := time.Now().UTC()
+			incident.ResolvedAt = &now
+		}
+		incident.Status = StatusResolved
+		err = s.store.UpdateIncident(ctx, incident)
+		if err != nil {
+			return fmt.Errorf("failed to store resolved incident: %w", err)
+		}
+		s.recordChanges(ctx, &before, incident, userID)
+		if !wasResolved {
+			s.webhooks.Publish(ctx, WebhookPayload{Event: WebhookIncidentResolved, Incident: incident})
+		}
+
+		newTopic := incidentTopic(incident)
 		err = s.slackService.SetChannelTopic(ctx, channelID, newTopic)
 		if err != nil {
 			slog.WarnContext(ctx, "Failed to set channel topic to resolved", "channelID", channelID, "newTopic", newTopic, "error", err)
 		}
+		s.updateBroadcast(ctx, incident)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Send ephemeral confirmation
+	// Send ephemeral confirmation, unless resolved through the API without a
+	// user.
+	if userID == "" {
+		return nil
+	}
 	err = s.slackService.PostEphemeralMessage(ctx, channelID, userID, []slack.Block{
 		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", ":white_check_mark: Incident marked as resolved.", false, false), nil, nil),
 	})
@@ -541,3 +986,72 @@ func (s *IncidentService) ResolveIncident(ctx context.Context, channelID, userID
 
 	return nil // Overall command success even if some non-critical parts fail (logged as warnings)
 }
+
+// ReopenIncident moves a resolved incident back to the status it had before
+// it was resolved and records the reason on the timeline. Its channel is
+// unarchived first if the lifecycle archived it, which is why this takes an
+// incident ID rather than a channel.
+func (s *IncidentService) ReopenIncident(ctx context.Context, incidentID, userID, reason string) (*Incident, error) {
+	var reopened *Incident
+	err := s.withIncidentID(ctx, incidentID, func(incident *Incident) error {
+		if incident.Status != StatusResolved {
+			return ErrIncidentNotResolved
+		}
+
+		if err := s.unarchiveIncidentChannel(ctx, incident); err != nil {
+			return err
+		}
+
+		before := *incident
+		incident.Status = incident.PreviousStatus
+		if _, ok := s.config().Taxonomy.Status(incident.Status); !ok || incident.Status == StatusResolved {
+			incident.Status = s.config().Taxonomy.InitialStatus()
+		}
+		markReopened(incident)
+		if err := s.store.UpdateIncident(ctx, incident); err != nil {
+			return fmt.Errorf("failed to store reopened incident: %w", err)
+		}
+		s.recordChanges(ctx, &before, incident, userID)
+		s.webhooks.Publish(ctx, WebhookPayload{Event: WebhookIncidentUpdated, Incident: incident})
+
+		if err := s.slackService.SetChannelTopic(ctx, incident.ChannelID, incidentTopic(incident)); err != nil {
+			slog.WarnContext(ctx, "Failed to set channel topic after reopening", "channelID", incident.ChannelID, "error", err)
+		}
+		s.updateBroadcast(ctx, incident)
+
+		timelineMsg := fmt.Sprintf("Incident reopened. Status: %s, Severity: %s", incident.Status, incident.Severity)
+		if reason != "" {
+			timelineMsg += " Reason: " + reason
+		}
+		if err := s.addTimelineItem(ctx, incident, userID, timelineMsg); err != nil {
+			slog.ErrorContext(ctx, "Failed to add reopened item to timeline", "incidentID", incident.ID, "error", err)
+		}
+
+		reopened = incident
+		return nil
+	})
+	return reopened, err
+}
+
+// unarchiveIncidentChannel brings back the archived channel of an incident
+// that is being reopened. The caller must hold the incident lock.
+func (s *IncidentService) unarchiveIncidentChannel(ctx context.Context, incident *Incident) error {
+	if incident.ArchivedAt == nil {
+		return nil
+	}
+	err := s.slackService.UnarchiveChannel(ctx, incident.ChannelID)
+	if err != nil && !strings.Contains(err.Error(), "not_archived") {
+		return fmt.Errorf("failed to unarchive channel: %w", err)
+	}
+	incident.ArchivedAt = nil
+	return nil
+}
+
+// markReopened resets the resolution state of an incident leaving Resolved,
+// so the lifecycle starts over when it is resolved again.
+func markReopened(incident *Incident) {
+	incident.ResolvedAt = nil
+	incident.ReopenCount++
+	incident.WrapUpTS = ""
+	incident.KeepChannel = false
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxListedIncidents keeps `/incident list` within Slack's 50 block limit.
+const maxListedIncidents = 40
+
+// parseListArgs reads the `[all|open|resolved] [sev]` arguments of
+// `/incident list`, in either order. It lists open incidents by default.
+func parseListArgs(taxonomy *Taxonomy, args string) (IncidentFilter, error) {
+	openStatuses := taxonomy.OpenStatuses()
+	filter := IncidentFilter{Statuses: openStatuses}
+	for _, arg := range strings.Fields(strings.ToLower(args)) {
+		switch arg {
+		case "all":
+			filter.Statuses = nil
+		case "open":
+			filter.Statuses = openStatuses
+		case "resolved":
+			filter.Statuses = []Status{StatusResolved}
+		default:
+			severity, ok := taxonomy.ParseSeverity(arg)
+			if !ok {
+				return IncidentFilter{}, fmt.Errorf("unknown filter %q", arg)
+			}
+			filter.Severity = severity
+		}
+	}
+	return filter, nil
+}
+
+// queryFilter builds the filter for the incident list API and CLI. status
+// is "open" (or empty), "all", "resolved" or a status ID; severity may be
+// empty and limit zero.
+func queryFilter(taxonomy *Taxonomy, status, severity string, limit int) (IncidentFilter, error) {
+	filter := IncidentFilter{Statuses: taxonomy.OpenStatuses(), Limit: limit}
+	switch status {
+	case "", "open":
+	case "all":
+		filter.Statuses = nil
+	case "resolved":
+		filter.Statuses = []Status{StatusResolved}
+	default:
+		if _, ok := taxonomy.Status(Status(status)); !ok {
+			return IncidentFilter{}, fmt.Errorf("unknown status %q", status)
+		}
+		filter.Statuses = []Status{Status(status)}
+	}
+	if severity != "" {
+		var ok bool
+		filter.Severity, ok = taxonomy.ParseSeverity(severity)
+		if !ok {
+			return IncidentFilter{}, fmt.Errorf("unknown severity %q", severity)
+		}
+	}
+	if limit < 0 {
+		return IncidentFilter{}, errors.New("limit must not be negative")
+	}
+	return filter, nil
+}
+
+// ListIncidents returns stored incidents matching filter, newest first.
+func (s *IncidentService) ListIncidents(ctx context.Context, filter IncidentFilter) ([]*Incident, error) {
+	incidents, err := s.store.ListIncidents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+// IncidentListMessage renders the `/incident list` response. Pass one more
+// incident than maxListedIncidents to get a note that the list was cut short.
+func (s *IncidentService) IncidentListMessage(incidents []*Incident, filter IncidentFilter, now time.Time) []slack.Block {
+	scope := "incidents"
+	switch {
+	case len(filter.Statuses) == 1 && filter.Statuses[0] == StatusResolved:
+		scope = "resolved incidents"
+	case len(filter.Statuses) > 0:
+		scope = "open incidents"
+	}
+	if filter.Severity != "" {
+		scope = string(filter.Severity) + " " + scope
+	}
+
+	if len(incidents) == 0 {
+		return []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("There are no %s.", scope), false, false), nil, nil),
+		}
+	}
+
+	truncated := len(incidents) > maxListedIncidents
+	if truncated {
+		incidents = incidents[:maxListedIncidents]
+	}
+	if filter.Severity == "" && len(filter.Statuses) > 0 && filter.Statuses[0] != StatusResolved {
+		// Open incidents are most useful worst first.
+		taxonomy := s.config().Taxonomy
+		sort.SliceStable(incidents, func(i, j int) bool {
+			return taxonomy.SeverityRank(incidents[i].Severity) < taxonomy.SeverityRank(incidents[j].Severity)
+		})
+	}
+
+	header := fmt.Sprintf("*%s* (%d)", strings.ToUpper(scope[:1])+scope[1:], len(incidents))
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", header, false, false), nil, nil)}
+	for _, incident := range incidents {
+		text := slack.NewTextBlockObject("mrkdwn", incidentListLine(s.config().Taxonomy, incident, now), false, false)
+		blocks = append(blocks, slack.NewSectionBlock(text, nil, nil))
+	}
+	if truncated {
+		note := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("Showing the %d most recent. Narrow the list with a status or severity, e.g. `/incident list resolved sev1`.", maxListedIncidents), false, false)
+		blocks = append(blocks, slack.NewContextBlock("", note))
+	}
+	return blocks
+}
+
+func incidentListLine(taxonomy *Taxonomy, incident *Incident, now time.Time) string {
+	channel := "#" + incident.ChannelName
+	if incident.ChannelID != "" {
+		channel = fmt.Sprintf("<#%s>", incident.ChannelID)
+	}
+	commander := "no commander"
+	if incident.CommanderID != "" {
+		commander = fmt.Sprintf("<@%s>", incident.CommanderID)
+	}
+	age := "opened " + formatAge(now.Sub(incident.CreatedAt)) + " ago"
+	if incident.ResolvedAt != nil {
+		age = "resolved " + formatAge(now.Sub(*incident.ResolvedAt)) + " ago, after " + formatAge(incident.ResolvedAt.Sub(incident.CreatedAt))
+	}
+
+	return fmt.Sprintf("*%s* %s · *%s* · %s\n%s\nCommander: %s · %s",
+		incident.ID, channel, taxonomy.SeverityLabel(incident.Severity), taxonomy.StatusLabel(incident.Status), incident.Description, commander, age)
+}
+
+// formatAge renders a duration at a glance: "45m", "3h 20m", "2d 4h".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
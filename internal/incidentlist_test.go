@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseListArgs(t *testing.T) {
+	taxonomy := DefaultTaxonomy()
+	openStatuses := taxonomy.OpenStatuses()
+	tests := []struct {
+		args    string
+		want    IncidentFilter
+		wantErr bool
+	}{
+		{args: "", want: IncidentFilter{Statuses: openStatuses}},
+		{args: "all", want: IncidentFilter{}},
+		{args: "resolved sev1", want: IncidentFilter{Statuses: []Status{StatusResolved}, Severity: SeveritySev1}},
+		{args: "SEV-0 open", want: IncidentFilter{Statuses: openStatuses, Severity: SeveritySev0}},
+		{args: "all 3", want: IncidentFilter{Severity: SeveritySev3}},
+		{args: "closed", wantErr: true},
+		{args: "sev7", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseListArgs(taxonomy, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseListArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseListArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	app := newTestApp(t)
+
+	app.interaction(t, createIncidentSubmission("V600", "Checkout is down", "SEV-2"))
+	app.interaction(t, createIncidentSubmission("V601", "Search is slow", "SEV-3"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 4
+	})
+	resolved := app.incidentNamed(t, "Search is slow")
+	app.command(t, resolved.ChannelID, "resolve")
+	eventually(t, "resolution", func() bool {
+		return app.incidentNamed(t, "Search is slow").Status == StatusResolved
+	})
+
+	list := func(args string) string {
+		t.Helper()
+		before := len(app.slack.Calls("response_url"))
+		app.command(t, "CGENERAL", strings.TrimSpace("list "+args))
+		eventually(t, "list response", func() bool {
+			return len(app.slack.Calls("response_url")) > before
+		})
+		blocks, _ := json.Marshal(app.slack.Calls("response_url")[before].JSON["blocks"])
+		return string(blocks)
+	}
+
+	if open := list(""); !strings.Contains(open, "Checkout is down") || strings.Contains(open, "Search is slow") {
+		t.Errorf("open list = %s", open)
+	}
+	if all := list("resolved"); !strings.Contains(all, "Search is slow") || !strings.Contains(all, "Resolved incidents") {
+		t.Errorf("resolved list = %s", all)
+	}
+	if none := list("sev0"); !strings.Contains(none, "There are no SEV-0 open incidents.") {
+		t.Errorf("sev0 list = %s", none)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Second:             "<1m",
+		45 * time.Minute:             "45m",
+		3*time.Hour + 20*time.Minute: "3h 20m",
+		52 * time.Hour:               "2d 4h",
+	} {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
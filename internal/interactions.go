@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/slack-go/slack"
+)
+
+// HandleInteraction processes block actions and modal submissions. Slack
+// retries of the same interaction are only processed once. The returned error
+// is for failures the caller should report to the user.
+func (s *IncidentService) HandleInteraction(ctx context.Context, interaction slack.InteractionCallback) error {
+	_, shared, err := s.idempotency.Do(ctx, interactionKey(interaction), func() (any, error) {
+		return nil, s.handleInteraction(ctx, interaction)
+	})
+	if shared {
+		// The original delivery did the work and reports its own failures.
+		slog.InfoContext(ctx, "Skipped duplicate interaction", "type", interaction.Type, "viewID", interaction.View.ID, "error", err)
+		return nil
+	}
+	return err
+}
+
+// interactionKey identifies an interaction across Slack retries. A modal
+// keeps its view ID across resubmissions; other interactions carry a
+// per-interaction trigger ID.
+func interactionKey(interaction slack.InteractionCallback) string {
+	if interaction.Type == slack.InteractionTypeViewSubmission && interaction.View.ID != "" {
+		return "view:" + interaction.View.ID
+	}
+	if interaction.TriggerID != "" {
+		return "trigger:" + interaction.TriggerID
+	}
+	return ""
+}
+
+// interactionOpensModal reports whether an interaction opens a modal, which
+// like the equivalent commands must happen before the trigger ID expires.
+func interactionOpensModal(interaction slack.InteractionCallback) bool {
+	if interaction.Type != slack.InteractionTypeBlockActions {
+		return false
+	}
+	for _, action := range interaction.ActionCallback.BlockActions {
+		if action.ActionID == declareIncidentActionID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *IncidentService) handleInteraction(ctx context.Context, interaction slack.InteractionCallback) error {
+	switch interaction.Type {
+	case slack.InteractionTypeBlockActions:
+		for _, action := range interaction.ActionCallback.BlockActions {
+			switch action.ActionID {
+			case declareIncidentActionID:
+				if err := s.CreateIncident(ctx, interaction.TriggerID); err != nil {
+					return fmt.Errorf("could not open dialog: %w", err)
+				}
+
+			case actionItemDoneActionID:
+				number, ok := actionItemNumberFromBlockID(action.BlockID)
+				if !ok {
+					slog.WarnContext(ctx, "Unexpected action item block ID", "blockID", action.BlockID)
+					continue
+				}
+				completed := len(action.SelectedOptions) > 0
+				_, err := s.SetActionItemCompleted(ctx, interaction.Channel.ID, interaction.User.ID, number, completed)
+				if err != nil {
+					return fmt.Errorf("failed to update action item %d: %w", number, err)
+				}
+
+			case acceptHandoffActionID, declineHandoffActionID:
+				if err := s.handleHandoffAnswer(ctx, interaction, action); err != nil {
+					return err
+				}
+
+			case joinIncidentActionID:
+				incident, err := s.JoinIncident(ctx, action.Value, interaction.User.ID)
+				if err != nil {
+					return fmt.Errorf("failed to join %s: %w", action.Value, err)
+				}
+				target := ResponseTarget{ResponseURL: interaction.ResponseURL, ChannelID: interaction.Channel.ID, UserID: interaction.User.ID}
+				if err := s.slackService.RespondText(ctx, target, fmt.Sprintf("You've joined <#%s>.", incident.ChannelID)); err != nil {
+					slog.WarnContext(ctx, "Failed to confirm joining incident", "incidentID", incident.ID, "error", err)
+				}
+			}
+		}
+
+	case slack.InteractionTypeViewSubmission:
+		switch interaction.View.CallbackID {
+		case "create_incident_modal":
+			return s.handleCreateIncidentSubmission(ctx, interaction)
+		case "update_incident_modal":
+			return s.handleUpdateIncidentSubmission(ctx, interaction)
+		}
+	}
+
+	return nil
+}
+
+func (s *IncidentService) handleCreateIncidentSubmission(ctx context.Context, interaction slack.InteractionCallback) error {
+	values := interaction.View.State.Values
+	status, severity, err := s.selectedLevels(values)
+	if err != nil {
+		return err
+	}
+	declaration := IncidentDeclaration{
+		Description:  values["description"]["description"].Value,
+		Status:       status,
+		Severity:     severity,
+		CommanderID:  selectedUser(values, "incident_commander"),
+		CommsRepID:   selectedUser(values, "comms_representative"),
+		Members:      append(values["incident_members"]["incident_members"].SelectedUsers, interaction.User.ID),
+		DeclaredBy:   interaction.User.ID,
+		Confidential: len(values["confidential"]["confidential"].SelectedOptions) > 0,
+	}
+
+	incident, err := s.DeclareIncident(ctx, declaration)
+	if err != nil {
+		return err
+	}
+
+	err = s.slackService.PostEphemeralMessage(ctx, incident.ChannelID, interaction.User.ID, s.HelpMessage().BlockSet)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to send help message", "error", err)
+	}
+	return nil
+}
+
+func (s *IncidentService) handleUpdateIncidentSubmission(ctx context.Context, interaction slack.InteractionCallback) error {
+	values := interaction.View.State.Values
+	channelID := interaction.View.PrivateMetadata
+	status, severity, err := s.selectedLevels(values)
+	if err != nil {
+		return err
+	}
+
+	err = s.UpdateIncidentDetails(
+		ctx,
+		channelID,
+		interaction.User.ID,
+		status,
+		severity,
+		s.selectedRoles(values),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update incident: %w", err)
+	}
+	return nil
+}
+
+// selectedLevels reads the status and severity selects of the create and
+// update modals. The taxonomy may have changed since the modal was opened, so
+// the IDs are checked against it.
+func (s *IncidentService) selectedLevels(values map[string]map[string]slack.BlockAction) (Status, Severity, error) {
+	status := Status(values["status"]["status"].SelectedOption.Value)
+	if _, ok := s.config().Taxonomy.Status(status); !ok {
+		return "", "", fmt.Errorf("unknown status %q", status)
+	}
+	severity := Severity(values["incident_severity"]["incident_severity"].SelectedOption.Value)
+	if _, ok := s.config().Taxonomy.Severity(severity); !ok {
+		return "", "", fmt.Errorf("unknown severity %q", severity)
+	}
+	return status, severity, nil
+}
+
+// selectedUser reads a users_select input whose block and action IDs are both
+// id.
+func selectedUser(values map[string]map[string]slack.BlockAction, id string) string {
+	state, ok := values[id][id]
+	if !ok {
+		return ""
+	}
+	if state.SelectedUser != "" { // For single user select, it's SelectedUser
+		return state.SelectedUser
+	}
+	if len(state.SelectedUsers) > 0 { // Fallback if it's behaving like multi-user select
+		return state.SelectedUsers[0]
+	}
+	return ""
+}
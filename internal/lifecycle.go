@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// RunLifecycle advances resolved incidents through their post-resolution
+// lifecycle every interval until ctx is done.
+func (s *IncidentService) RunLifecycle(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ProcessLifecycle(ctx, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "Incident lifecycle run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessLifecycle posts a wrap-up to newly resolved incidents and archives
+// the channels of those whose wrap-up is older than their severity's
+// ArchiveAfter, unless someone reacted to the wrap-up to keep the channel.
+func (s *IncidentService) ProcessLifecycle(ctx context.Context, now time.Time) error {
+	incidents, err := s.store.ListIncidents(ctx, IncidentFilter{Statuses: []Status{StatusResolved}, Unarchived: true})
+	if err != nil {
+		return fmt.Errorf("failed to list resolved incidents: %w", err)
+	}
+
+	var errs []error
+	for _, incident := range incidents {
+		if incident.KeepChannel {
+			continue
+		}
+		if err := s.advanceLifecycle(ctx, incident.ID, now); err != nil {
+			errs = append(errs, fmt.Errorf("incident %s: %w", incident.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *IncidentService) advanceLifecycle(ctx context.Context, incidentID string, now time.Time) error {
+	unlock := s.locks.Lock(incidentID)
+	defer unlock()
+
+	// Reload under the lock; the incident may have changed since it was listed.
+	incident, err := s.store.GetIncident(ctx, incidentID)
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+	if incident.Status != StatusResolved || incident.ArchivedAt != nil || incident.KeepChannel {
+		return nil
+	}
+
+	level, _ := s.config().Taxonomy.Severity(incident.Severity)
+	grace := level.ArchiveAfter
+	if incident.WrapUpTS == "" {
+		return s.postWrapUp(ctx, incident, now, grace)
+	}
+
+	// The grace period runs from the wrap-up, so the channel always gets
+	// the notice it promises.
+	postedAt, ok := slackTimestampTime(incident.WrapUpTS)
+	if grace <= 0 || !ok || now.Before(postedAt.Add(grace)) {
+		return nil
+	}
+
+	reactions, err := s.slackService.GetReactions(ctx, incident.ChannelID, incident.WrapUpTS)
+	if err != nil {
+		return err
+	}
+	if len(reactions) > 0 {
+		incident.KeepChannel = true
+		if err := s.store.UpdateIncident(ctx, incident); err != nil {
+			return fmt.Errorf("failed to store incident: %w", err)
+		}
+		_, err := s.slackService.PostMessage(ctx, incident.ChannelID, []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "Someone reacted to the wrap-up, so I'll leave this channel open.", false, false), nil, nil),
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to post keep-channel notice", "incidentID", incident.ID, "error", err)
+		}
+		return nil
+	}
+
+	return s.archiveIncident(ctx, incident, now)
+}
+
+func (s *IncidentService) postWrapUp(ctx context.Context, incident *Incident, now time.Time, grace time.Duration) error {
+	timeline, err := s.store.ListTimelineItems(ctx, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load timeline: %w", err)
+	}
+	items, err := s.store.ListActionItems(ctx, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load action items: %w", err)
+	}
+
+	ts, err := s.slackService.PostMessage(ctx, incident.ChannelID, wrapUpBlocks(incident, timeline, items, now, grace))
+	if err != nil {
+		return fmt.Errorf("failed to post wrap-up: %w", err)
+	}
+
+	incident.WrapUpTS = ts
+	if err := s.store.UpdateIncident(ctx, incident); err != nil {
+		return fmt.Errorf("failed to store wrap-up: %w", err)
+	}
+	slog.InfoContext(ctx, "Posted incident wrap-up", "incidentID", incident.ID, "channelID", incident.ChannelID)
+	return nil
+}
+
+func wrapUpBlocks(incident *Incident, timeline []TimelineItem, items []ActionItem, now time.Time, grace time.Duration) []slack.Block {
+	mrkdwn := func(text string) *slack.SectionBlock {
+		return slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil)
+	}
+
+	summary := fmt.Sprintf("*:checkered_flag: %s wrap-up*\n%s\n*Severity:* %s · *Time to resolve:* %s · *Timeline entries:* %d",
+		incident.ID, incident.Description, incident.Severity, formatAge(incident.ResolvedAt.Sub(incident.CreatedAt)), len(timeline))
+	blocks := []slack.Block{mrkdwn(summary)}
+
+	var open []string
+	for _, item := range items {
+		if !item.Completed {
+			open = append(open, "• "+actionItemLine(item))
+		}
+	}
+	switch {
+	case len(open) > 0:
+		header := fmt.Sprintf("*:memo: Open action items (%d)*", len(open))
+		// Keep the reminder comfortably under Slack's 3000 character limit.
+		if len(open) > 20 {
+			open = append(open[:20], fmt.Sprintf("…and %d more. Use `/incident ai list` to see them all.", len(open)-20))
+		}
+		blocks = append(blocks, mrkdwn(header+"\n"+strings.Join(open, "\n")))
+	case len(items) > 0:
+		blocks = append(blocks, mrkdwn(":white_check_mark: All action items are done."))
+	}
+
+	if grace > 0 {
+		archiveAt := now.Add(grace)
+		notice := fmt.Sprintf("I'll archive this channel <!date^%d^{date_short_pretty} at {time}|%s>. React to this message to keep it open.",
+			archiveAt.Unix(), archiveAt.UTC().Format(time.RFC1123))
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", notice, false, false)))
+	}
+	return blocks
+}
+
+func (s *IncidentService) archiveIncident(ctx context.Context, incident *Incident, now time.Time) error {
+	// Record it while the channel still accepts messages.
+	if err := s.addTimelineItem(ctx, incident, "", "Channel archived."); err != nil {
+		slog.WarnContext(ctx, "Failed to add archive item to timeline", "incidentID", incident.ID, "error", err)
+	}
+
+	err := s.slackService.ArchiveChannel(ctx, incident.ChannelID)
+	if err != nil && !strings.Contains(err.Error(), "already_archived") {
+		return err
+	}
+
+	archivedAt := now.UTC()
+	incident.ArchivedAt = &archivedAt
+	if err := s.store.UpdateIncident(ctx, incident); err != nil {
+		return fmt.Errorf("failed to store archived incident: %w", err)
+	}
+	slog.InfoContext(ctx, "Archived incident channel", "incidentID", incident.ID, "channelID", incident.ChannelID)
+	return nil
+}
+
+// slackTimestampTime converts a message timestamp ("1700000000.000100"),
+// which is seconds since the epoch, to a time.
+func slackTimestampTime(ts string) (time.Time, bool) {
+	seconds, _, _ := strings.Cut(ts, ".")
+	unix, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	app := newTestApp(t)
+	app.archiveAfter(SeveritySev3, 48*time.Hour)
+	app.archiveAfter(SeveritySev1, 0)
+	ctx := context.Background()
+
+	app.interaction(t, createIncidentSubmission("V800", "Search is slow", "SEV-3"))
+	app.interaction(t, createIncidentSubmission("V801", "Checkout is down", "SEV-1"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 4
+	})
+	checkout, search := app.incidentNamed(t, "Checkout is down"), app.incidentNamed(t, "Search is slow")
+
+	app.command(t, search.ChannelID, "ai write a runbook")
+	app.command(t, search.ChannelID, "resolve")
+	app.command(t, checkout.ChannelID, "resolve")
+	eventually(t, "resolution", func() bool {
+		resolved, _ := app.store.ListIncidents(ctx, IncidentFilter{Statuses: []Status{StatusResolved}})
+		return len(resolved) == 2
+	})
+
+	// The fake's message timestamps are in November 2023.
+	start := time.Unix(1700000000, 0)
+	if err := app.service.ProcessLifecycle(ctx, start); err != nil {
+		t.Fatalf("ProcessLifecycle: %v", err)
+	}
+	search, _ = app.store.GetIncident(ctx, search.ID)
+	checkout, _ = app.store.GetIncident(ctx, checkout.ID)
+	if search.WrapUpTS == "" || checkout.WrapUpTS == "" {
+		t.Fatalf("wrap-ups not recorded: %q, %q", search.WrapUpTS, checkout.WrapUpTS)
+	}
+	var wrapUp string
+	for _, call := range app.slack.Calls("chat.postMessage") {
+		if call.Form.Get("channel") == search.ChannelID && strings.Contains(call.Form.Get("blocks"), "wrap-up") {
+			wrapUp = call.Form.Get("blocks")
+		}
+	}
+	for _, want := range []string{"Open action items (1)", "write a runbook", "React to this message to keep it open"} {
+		if !strings.Contains(wrapUp, want) {
+			t.Errorf("wrap-up is missing %q: %s", want, wrapUp)
+		}
+	}
+
+	// Nothing happens during the grace period.
+	if err := app.service.ProcessLifecycle(ctx, start.Add(time.Hour)); err != nil {
+		t.Fatalf("ProcessLifecycle: %v", err)
+	}
+	if calls := app.slack.Calls("conversations.archive"); len(calls) != 0 {
+		t.Fatalf("archived during the grace period: %v", calls)
+	}
+
+	app.slack.React(checkout.ChannelID, checkout.WrapUpTS, "eyes")
+	app.archiveAfter(SeveritySev1, 24*time.Hour)
+	if err := app.service.ProcessLifecycle(ctx, start.Add(49*time.Hour)); err != nil {
+		t.Fatalf("ProcessLifecycle: %v", err)
+	}
+
+	if !app.slack.Channel(search.ChannelID).Archived {
+		t.Error("SEV-3 channel was not archived after its grace period")
+	}
+	if search, _ = app.store.GetIncident(ctx, search.ID); search.ArchivedAt == nil {
+		t.Error("archived incident has no ArchivedAt")
+	}
+	if app.slack.Channel(checkout.ChannelID).Archived {
+		t.Error("channel was archived although someone reacted to the wrap-up")
+	}
+	if checkout, _ = app.store.GetIncident(ctx, checkout.ID); !checkout.KeepChannel {
+		t.Error("reacted-to incident is not marked KeepChannel")
+	}
+}
@@ -5,17 +5,86 @@ import (
 )
 
 type Incident struct {
-	ID          string    `json:"id"`
-	Description string    `json:"description"`
-	Status      Status    `json:"status"`
-	Severity    Severity  `json:"severity"`
-	CreatedBy   string    `json:"created_by"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	ChannelID   string    `json:"channel_id"`
-	Members     []string  `json:"members"`
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      Status `json:"status"`
+	// PreviousStatus is the status before the incident was resolved, which
+	// reopening restores.
+	PreviousStatus Status   `json:"previous_status,omitempty"`
+	ReopenCount    int      `json:"reopen_count"`
+	Severity       Severity `json:"severity"`
+	CommanderID    string   `json:"commander_id,omitempty"`
+	CommsRepID     string   `json:"comms_rep_id,omitempty"`
+	// Roles holds who has each of the other roles in the taxonomy, by role
+	// ID. Use RoleHolder to look up any role.
+	Roles      map[string]string `json:"roles,omitempty"`
+	CreatedBy  string            `json:"created_by"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	ResolvedAt *time.Time        `json:"resolved_at,omitempty"`
+	// ArchivedAt is set while the incident channel is archived.
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	ChannelID   string     `json:"channel_id"`
+	ChannelName string     `json:"channel_name"`
+	Members     []string   `json:"members"`
+	// TimelineTS and ActionItemsTS are the timestamps of the pinned timeline
+	// and action item messages in ChannelID.
+	TimelineTS    string `json:"-"`
+	ActionItemsTS string `json:"-"`
+	// WrapUpTS is the timestamp of the post-resolution wrap-up message.
+	// Reacting to it sets KeepChannel, which stops HAL archiving the channel.
+	WrapUpTS    string `json:"-"`
+	KeepChannel bool   `json:"keep_channel"`
+	// Confidential incidents get a private channel, aren't announced and
+	// are only listed to the people involved in them.
+	Confidential bool `json:"confidential"`
+	// BroadcastChannelID and BroadcastTS locate the announcement card posted
+	// to the broadcast channel, if any.
+	BroadcastChannelID string `json:"-"`
+	BroadcastTS        string `json:"-"`
+}
+
+// The built-in roles, which every taxonomy has. Their holders are kept in
+// Incident.CommanderID and CommsRepID.
+const (
+	RoleCommander = "commander"
+	RoleComms     = "comms"
+	RoleScribe    = "scribe"
+	RoleTechLead  = "tech_lead"
+)
+
+// RoleHolder returns who has a role, or "" if nobody does.
+func (i *Incident) RoleHolder(role string) string {
+	switch role {
+	case RoleCommander:
+		return i.CommanderID
+	case RoleComms:
+		return i.CommsRepID
+	}
+	return i.Roles[role]
 }
 
+// setRoleHolder assigns a role, or unassigns it if userID is empty.
+func (i *Incident) setRoleHolder(role, userID string) {
+	switch role {
+	case RoleCommander:
+		i.CommanderID = userID
+	case RoleComms:
+		i.CommsRepID = userID
+	default:
+		if userID == "" {
+			delete(i.Roles, role)
+			return
+		}
+		if i.Roles == nil {
+			i.Roles = make(map[string]string)
+		}
+		i.Roles[role] = userID
+	}
+}
+
+// Status is the ID of a status in the Taxonomy. The constants are the IDs in
+// the default taxonomy; StatusResolved is required by every taxonomy.
 type Status string
 
 const (
@@ -25,6 +94,8 @@ const (
 	StatusResolved      Status = "Resolved"
 )
 
+// Severity is the ID of a severity in the Taxonomy. The constants are the
+// IDs in the default taxonomy.
 type Severity string
 
 const (
@@ -38,16 +109,89 @@ const (
 	SeveritySev3 Severity = "SEV-3"
 )
 
-type TimelineItem struct {
-	Timestamp time.Time `json:"timestamp"`
-	Message   string    `json:"message"`
+// IncidentChange records one field of an incident changing, so its history
+// can be reconstructed. The creation of an incident is recorded as changes
+// from empty values.
+type IncidentChange struct {
+	ID         int64       `json:"id"`
+	IncidentID string      `json:"incident_id"`
+	Field      ChangeField `json:"field"`
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	User       string      `json:"user"`
+	ChangedAt  time.Time   `json:"changed_at"`
+}
+
+type ChangeField string
+
+const (
+	ChangeStatus    ChangeField = "status"
+	ChangeSeverity  ChangeField = "severity"
+	ChangeCommander ChangeField = "commander"
+	ChangeCommsRep  ChangeField = "comms_rep"
+)
+
+// AuditEntry records an access-control decision about a confidential
+// incident.
+type AuditEntry struct {
+	ID         int64       `json:"id"`
+	IncidentID string      `json:"incident_id"`
+	Action     AuditAction `json:"action"`
+	// User is who the decision was about, or who made it for actions HAL
+	// takes on their behalf.
 	User      string    `json:"user"`
+	Allowed   bool      `json:"allowed"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditAction string
+
+const (
+	AuditPrivateChannel   AuditAction = "private_channel"
+	AuditBroadcastSkipped AuditAction = "broadcast_skipped"
+	AuditList             AuditAction = "list"
+	AuditJoin             AuditAction = "join"
+	AuditRead             AuditAction = "read"
+	AuditHandoff          AuditAction = "handoff"
+	AuditReopen           AuditAction = "reopen"
+	AuditWebhook          AuditAction = "webhook"
+)
+
+// IncidentAlert links an Alertmanager alert, by fingerprint, to the incident
+// it was routed to.
+type IncidentAlert struct {
+	Fingerprint string    `json:"fingerprint"`
+	IncidentID  string    `json:"incident_id"`
+	GroupKey    string    `json:"group_key"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	StartsAt    time.Time `json:"starts_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type TimelineItem struct {
+	ID         int64     `json:"id"`
+	IncidentID string    `json:"incident_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Message    string    `json:"message"`
+	User       string    `json:"user"`
 }
 
 type ActionItem struct {
+	ID         int64  `json:"id"`
+	IncidentID string `json:"incident_id"`
+	// Number is the item's position within its incident, starting at 1. It is
+	// what users type in `/incident ai done <n>`.
+	Number      int    `json:"number"`
 	Description string `json:"description"`
-	User        string `json:"user"`
-	Completed   bool   `json:"completed"`
+	// User is who added the item and Assignee, if anyone, who it is for.
+	User        string     `json:"user"`
+	Assignee    string     `json:"assignee,omitempty"`
+	Completed   bool       `json:"completed"`
+	CompletedBy string     `json:"completed_by,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 type SlackCommandRequest struct {
@@ -66,11 +210,75 @@ type SlackCommandRequest struct {
 	TriggerId           string `form:"trigger_id" json:"trigger_id"`
 }
 
+// Config is read from an optional YAML or TOML file and then from
+// environment variables. File keys are the lower-cased environment variable
+// names, e.g. `job_timeout: 2m` or JOB_TIMEOUT=2m.
 type Config struct {
-	SlackToken         string
-	SlackSigningSecret string
-	ServerPort         int
-	ServerHost         string
-	Environment        string
-	LogLevel           string
+	SlackToken         string `yaml:"slack_token"`
+	SlackSigningSecret string `yaml:"slack_signing_secret"`
+	// SlackAppToken is the app-level token (xapp-...) used in Socket Mode.
+	SlackAppToken   string        `yaml:"slack_app_token"`
+	SocketMode      bool          `yaml:"slack_socket_mode"`
+	ServerPort      int           `yaml:"server_port"`
+	ServerHost      string        `yaml:"server_host"`
+	Environment     string        `yaml:"environment"`
+	LogLevel        string        `yaml:"log_level"`
+	DatabasePath    string        `yaml:"database_path"`
+	WorkerCount     int           `yaml:"worker_count"`
+	WorkQueueSize   int           `yaml:"work_queue_size"`
+	JobTimeout      time.Duration `yaml:"job_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	IdempotencyTTL  time.Duration `yaml:"idempotency_ttl"`
+	// LifecycleInterval is how often resolved incidents are checked for a
+	// wrap-up or archival; zero disables the lifecycle.
+	LifecycleInterval time.Duration `yaml:"lifecycle_interval"`
+	// ChannelPrefix starts every incident channel name.
+	ChannelPrefix string `yaml:"channel_prefix"`
+	// ChannelTemplate follows ChannelPrefix in incident channel names. It
+	// may use the tokens {date}, {seq}, {sev}, {slug(description)} and {id},
+	// and needs {seq} or {id} to keep names unique.
+	ChannelTemplate string `yaml:"channel_template"`
+	// Taxonomy defines the incident severities and statuses.
+	Taxonomy *Taxonomy `yaml:"taxonomy"`
+	// Webhooks receive signed JSON payloads when incidents change.
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	// WebhookMaxAttempts is how many times a delivery is tried before it is
+	// dead-lettered. WebhookRetryDelay is the wait before the first retry,
+	// which doubles for each further attempt.
+	WebhookMaxAttempts int           `yaml:"webhook_max_attempts"`
+	WebhookRetryDelay  time.Duration `yaml:"webhook_retry_delay"`
+	// APIToken is the bearer token for the REST API. Empty disables the API.
+	APIToken string `yaml:"api_token"`
+	// PostmortemTemplate is the path of a Go text/template for `/incident
+	// postmortem`. Empty uses the built-in template.
+	PostmortemTemplate string `yaml:"postmortem_template"`
+	// BroadcastChannel is the channel, by ID or name, where every new
+	// incident is announced. Empty disables announcements.
+	BroadcastChannel string `yaml:"broadcast_channel"`
+	// Alertmanager configures the inbound Prometheus Alertmanager webhook.
+	Alertmanager AlertmanagerConfig `yaml:"alertmanager"`
+}
+
+type AlertmanagerConfig struct {
+	// Token is the bearer token Alertmanager sends. Empty disables the
+	// endpoint.
+	Token string `yaml:"token"`
+	// SeverityLabel is the alert label that picks the incident severity.
+	SeverityLabel string `yaml:"severity_label"`
+	// Severities maps values of SeverityLabel to severities. Values that
+	// aren't mapped are matched against the severities themselves, and then
+	// fall back to DefaultSeverity, or the least severe level if that's empty.
+	Severities      map[string]Severity `yaml:"severities"`
+	DefaultSeverity Severity            `yaml:"default_severity"`
+	// Invite lists users to add to incidents declared from alerts.
+	Invite []string `yaml:"invite"`
+}
+
+type WebhookConfig struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+	// Events limits the webhook to these events; empty means all of them.
+	Events []WebhookEvent `yaml:"events"`
+	// IncludeConfidential sends the webhook confidential incidents too.
+	IncludeConfidential bool `yaml:"include_confidential"`
 }
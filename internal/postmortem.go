@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultPostmortemTemplate is used when no postmortem_template is
+// configured. Sections HAL can't know about are left for the team to fill in.
+const defaultPostmortemTemplate = `# Postmortem: {{.Incident.Description}}
+
+| | |
+| --- | --- |
+| Incident | {{.Incident.ID}} |
+| Severity | {{.Severity.Label}} |
+| Status | {{.Status.Label}} |
+| Incident commander | {{user .Incident.CommanderID}} |
+| Comms representative | {{user .Incident.CommsRepID}} |
+| Channel | #{{.Incident.ChannelName}} |
+
+## Summary
+
+{{.Incident.Description}}
+
+_Describe what happened, who was affected and how it was resolved._
+
+## Impact
+
+- **Started:** {{time .ImpactStart}}
+- **Ended:** {{if .ImpactEnd}}{{time .ImpactEnd}}{{else}}ongoing{{end}}
+- **Duration:** {{duration .Duration}}
+
+## Severity Changes
+{{range .SeverityChanges}}
+- {{time .ChangedAt}}: {{if .From}}{{severity .From}} → {{end}}{{severity .To}}{{if .User}} by {{user .User}}{{end}}
+{{- end}}
+
+## Timeline
+{{range .Timeline}}
+- {{time .Timestamp}} {{.Message}}{{if .User}} ({{user .User}}){{end}}
+{{- end}}
+
+## Action Items
+{{range .ActionItems}}
+- [{{if .Completed}}x{{else}} {{end}}] {{.Description}}{{if .User}} ({{user .User}}){{end}}{{if .Assignee}}, assigned to {{user .Assignee}}{{end}}
+{{- else}}
+None.
+{{- end}}
+
+## Root Cause
+
+_What caused the incident?_
+
+## Lessons Learned
+
+_What went well, what went badly, and where did we get lucky?_
+`
+
+// PostmortemData is what postmortem templates are executed with.
+type PostmortemData struct {
+	Incident *Incident
+	Severity SeverityLevel
+	Status   StatusLevel
+	// ImpactStart is when the incident was declared and ImpactEnd when it was
+	// resolved, or nil while it is open. Duration runs until ImpactEnd or
+	// GeneratedAt.
+	ImpactStart time.Time
+	ImpactEnd   *time.Time
+	Duration    time.Duration
+	// Changes is the incident's full change history; SeverityChanges and
+	// StatusChanges are its severity and status changes, starting with the
+	// values it was declared with.
+	Changes         []IncidentChange
+	SeverityChanges []IncidentChange
+	StatusChanges   []IncidentChange
+	Timeline        []TimelineItem
+	ActionItems     []ActionItem
+	GeneratedAt     time.Time
+}
+
+// postmortemFuncs are available in postmortem templates: time formats a
+// time.Time or *time.Time in UTC, duration renders a time.Duration like
+// "3h 20m", and user renders a Slack user ID, or "none" if it is empty.
+var postmortemFuncs = template.FuncMap{
+	"time": func(t any) string {
+		switch t := t.(type) {
+		case time.Time:
+			return t.UTC().Format(timelineTimeFormat)
+		case *time.Time:
+			if t != nil {
+				return t.UTC().Format(timelineTimeFormat)
+			}
+		}
+		return ""
+	},
+	"duration": formatAge,
+	"user": func(id string) string {
+		if id == "" {
+			return "none"
+		}
+		return "@" + id
+	},
+}
+
+// loadPostmortemTemplate parses the template at path, or the default
+// template if path is empty. Besides postmortemFuncs, templates can use
+// severity and status, which render a severity or status ID with its label
+// from taxonomy.
+func loadPostmortemTemplate(path string, taxonomy *Taxonomy) (*template.Template, error) {
+	text := defaultPostmortemTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read postmortem template: %w", err)
+		}
+		text = string(data)
+	}
+	labels := template.FuncMap{
+		"severity": func(id any) string { return taxonomy.SeverityLabel(Severity(fmt.Sprint(id))) },
+		"status":   func(id any) string { return taxonomy.StatusLabel(Status(fmt.Sprint(id))) },
+	}
+	tmpl, err := template.New("postmortem").Funcs(postmortemFuncs).Funcs(labels).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postmortem template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Postmortem renders the postmortem document for an incident.
+func (s *IncidentService) Postmortem(ctx context.Context, incidentID string) (string, error) {
+	config := s.config()
+	tmpl, err := loadPostmortemTemplate(config.PostmortemTemplate, config.Taxonomy)
+	if err != nil {
+		return "", err
+	}
+
+	incident, err := s.store.GetIncident(ctx, incidentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load incident: %w", err)
+	}
+	changes, err := s.store.ListIncidentChanges(ctx, incident.ID)
+	if err != nil {
+		return "", err
+	}
+	timeline, err := s.store.ListTimelineItems(ctx, incident.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list timeline: %w", err)
+	}
+	items, err := s.store.ListActionItems(ctx, incident.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list action items: %w", err)
+	}
+
+	data := PostmortemData{
+		Incident:    incident,
+		ImpactStart: incident.CreatedAt,
+		ImpactEnd:   incident.ResolvedAt,
+		Changes:     changes,
+		Timeline:    timeline,
+		ActionItems: items,
+		GeneratedAt: time.Now().UTC(),
+	}
+	data.Severity, _ = config.Taxonomy.Severity(incident.Severity)
+	if data.Severity.ID == "" {
+		data.Severity = SeverityLevel{ID: incident.Severity, Label: string(incident.Severity)}
+	}
+	data.Status, _ = config.Taxonomy.Status(incident.Status)
+	if data.Status.ID == "" {
+		data.Status = StatusLevel{ID: incident.Status, Label: string(incident.Status)}
+	}
+	end := data.GeneratedAt
+	if incident.ResolvedAt != nil {
+		end = *incident.ResolvedAt
+	}
+	data.Duration = end.Sub(incident.CreatedAt)
+	for _, change := range changes {
+		switch change.Field {
+		case ChangeSeverity:
+			data.SeverityChanges = append(data.SeverityChanges, change)
+		case ChangeStatus:
+			data.StatusChanges = append(data.StatusChanges, change)
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render postmortem: %w", err)
+	}
+	return b.String(), nil
+}
+
+// PostPostmortem generates the postmortem for the channel's incident and
+// shares it in the channel as a Markdown file.
+func (s *IncidentService) PostPostmortem(ctx context.Context, channelID, userID string) error {
+	incident, err := s.incidentForChannel(ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+	doc, err := s.Postmortem(ctx, incident.ID)
+	if err != nil {
+		return err
+	}
+
+	comment := fmt.Sprintf(":memo: Postmortem draft for %s, generated from the incident record. Fill in the summary, root cause and lessons learned.", incident.ID)
+	err = s.slackService.UploadFile(ctx, channelID, incident.ID+"-postmortem.md", "Postmortem: "+incident.Description, doc, comment)
+	if err != nil {
+		return err
+	}
+	return s.AddTimelineItem(ctx, channelID, userID, "Postmortem draft generated.")
+}
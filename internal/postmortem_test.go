@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPostmortem(t *testing.T) {
+	app := newTestApp(t)
+
+	app.interaction(t, createIncidentSubmission("V1300", "Payments failing", "SEV-2"))
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	incident := app.incident()
+	path := "/incidents/" + incident.ID
+
+	if code := app.api(t, http.MethodPatch, path, `{"severity": "SEV-1", "commander_id": "U0IC", "user_id": "U0IC"}`, nil); code != http.StatusOK {
+		t.Fatalf("patch: status %d", code)
+	}
+	app.command(t, incident.ChannelID, "timeline card processor timing out")
+	app.command(t, incident.ChannelID, "resolve failed over to the backup processor")
+	eventually(t, "incident resolved", func() bool { return app.incident().Status == StatusResolved })
+
+	app.command(t, incident.ChannelID, "postmortem")
+	eventually(t, "postmortem uploaded", func() bool { return len(app.slack.Calls("files.completeUploadExternal")) == 1 })
+
+	complete := app.slack.Calls("files.completeUploadExternal")[0]
+	if complete.Form.Get("channel_id") != incident.ChannelID || !strings.Contains(complete.Form.Get("files"), "Postmortem: Payments failing") {
+		t.Errorf("upload shared as %v", complete.Form)
+	}
+	if name := app.slack.Calls("files.getUploadURLExternal")[0].Form.Get("filename"); name != incident.ID+"-postmortem.md" {
+		t.Errorf("filename = %q", name)
+	}
+	doc := app.slack.Calls("file_upload")[0].Form.Get("content")
+	for _, want := range []string{
+		"# Postmortem: Payments failing",
+		"| Severity | SEV-1 |",
+		"| Incident commander | @U0IC |",
+		"| Comms representative | none |",
+		"- **Ended:** 20",
+		": SEV-2 by @U1\n",
+		": SEV-2 → SEV-1 by @U0IC",
+		"card processor timing out",
+		"Incident resolved. Resolution: failed over to the backup processor",
+		"- [ ] Create incident postmortem",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("postmortem missing %q:\n%s", want, doc)
+		}
+	}
+	eventually(t, "timeline entry", func() bool {
+		return strings.Contains(app.slack.PinnedText(incident.ChannelID), "Postmortem draft generated.")
+	})
+
+	// The API serves the same document, rendered with the configured
+	// template.
+	template := filepath.Join(t.TempDir(), "postmortem.md.tmpl")
+	if err := os.WriteFile(template, []byte("{{.Incident.ID}} lasted {{duration .Duration}}; {{len .StatusChanges}} status changes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	app.config.PostmortemTemplate = template
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1"+path+"/postmortem", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	rec := httptest.NewRecorder()
+	app.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != incident.ID+" lasted <1m; 2 status changes" {
+		t.Errorf("API postmortem: status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("work queue is full")
+	ErrQueueClosed = errors.New("work queue is shut down")
+)
+
+// WorkQueue runs Slack-triggered work in the background so handlers can
+// acknowledge requests within Slack's 3-second window.
+type WorkQueue struct {
+	jobs    chan job
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type job struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewWorkQueue starts workers goroutines consuming a queue of the given size.
+// Each job gets its own context that expires after timeout.
+func NewWorkQueue(workers, size int, timeout time.Duration) *WorkQueue {
+	q := &WorkQueue{
+		jobs:    make(chan job, size),
+		timeout: timeout,
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue schedules fn to run in the background. It never blocks; if the queue
+// is full or shutting down an error is returned and fn is not run.
+func (q *WorkQueue) Enqueue(name string, fn func(ctx context.Context) error) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- job{name: name, fn: fn}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *WorkQueue) work() {
+	defer q.wg.Done()
+	for j := range q.jobs {
+		q.run(j)
+	}
+}
+
+func (q *WorkQueue) run(j job) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "Background job panicked", "job", j.name, "panic", r)
+		}
+	}()
+
+	start := time.Now()
+	if err := j.fn(ctx); err != nil {
+		slog.ErrorContext(ctx, "Background job failed", "job", j.name, "duration", time.Since(start), "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "Background job finished", "job", j.name, "duration", time.Since(start))
+}
+
+// Shutdown stops accepting new jobs and waits for queued and running jobs to
+// finish, or for ctx to expire.
+func (q *WorkQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("work queue did not drain: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReopen(t *testing.T) {
+	app := newTestApp(t)
+	app.archiveAfter(SeveritySev3, time.Hour)
+	ctx := context.Background()
+
+	app.interaction(t, createIncidentSubmission("V900", "Search is slow", "SEV-3"))
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 2
+	})
+	incident := app.incident()
+
+	app.command(t, incident.ChannelID, "reopen")
+	eventually(t, "not-resolved reply", func() bool {
+		calls := app.slack.Calls("response_url")
+		return len(calls) == 1 && strings.Contains(calls[0].JSON["blocks"].([]any)[0].(map[string]any)["text"].(map[string]any)["text"].(string), "isn't resolved")
+	})
+
+	app.command(t, incident.ChannelID, "resolve")
+	eventually(t, "resolution", func() bool { return app.incident().Status == StatusResolved })
+	app.command(t, incident.ChannelID, "reopen Latency is Back")
+	// The topic is set after the incident is stored.
+	eventually(t, "reopen", func() bool {
+		return app.incident().Status == StatusInvestigating && strings.HasPrefix(app.slack.Channel(incident.ChannelID).Topic, "SEV-3 incident:")
+	})
+
+	incident = app.incident()
+	if incident.ReopenCount != 1 || incident.ResolvedAt != nil {
+		t.Fatalf("reopened incident = %+v", incident)
+	}
+
+	// Resolve again and let the lifecycle archive the channel.
+	app.command(t, incident.ChannelID, "resolve")
+	eventually(t, "resolution", func() bool { return app.incident().Status == StatusResolved })
+	start := time.Unix(1700000000, 0)
+	for _, now := range []time.Time{start, start.Add(2 * time.Hour)} {
+		if err := app.service.ProcessLifecycle(ctx, now); err != nil {
+			t.Fatalf("ProcessLifecycle: %v", err)
+		}
+	}
+	if !app.slack.Channel(incident.ChannelID).Archived {
+		t.Fatal("channel was not archived")
+	}
+
+	// The archived channel can't run commands, so reopen by ID from elsewhere.
+	app.command(t, "CGENERAL", "reopen "+strings.ToLower(incident.ID)+" regression")
+	eventually(t, "reopen by ID", func() bool {
+		return strings.Contains(app.slack.PinnedText(incident.ChannelID), "Reason: regression")
+	})
+
+	incident = app.incident()
+	if incident.ReopenCount != 2 || incident.ArchivedAt != nil || incident.WrapUpTS != "" {
+		t.Fatalf("reopened incident = %+v", incident)
+	}
+	if app.slack.Channel(incident.ChannelID).Archived {
+		t.Fatal("channel is still archived")
+	}
+
+	timeline, _ := app.store.ListTimelineItems(ctx, incident.ID)
+	var reopens []string
+	for _, item := range timeline {
+		if strings.HasPrefix(item.Message, "Incident reopened") {
+			reopens = append(reopens, item.Message)
+		}
+	}
+	want := []string{
+		"Incident reopened. Status: Investigating, Severity: SEV-3 Reason: Latency is Back",
+		"Incident reopened. Status: Investigating, Severity: SEV-3 Reason: regression",
+	}
+	if strings.Join(reopens, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("reopen entries = %q, want %q", reopens, want)
+	}
+}
+
+func TestReopenConfidentialByOutsider(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	var secret Incident
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Suspicious logins", "severity": "SEV-2", "user_id": "U7", "confidential": true}`, &secret); code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+	if code := app.api(t, http.MethodPost, "/incidents/"+secret.ID+"/resolve", "", nil); code != http.StatusOK {
+		t.Fatalf("resolve: status %d", code)
+	}
+
+	// U1 isn't involved, so is told the incident doesn't exist.
+	app.command(t, "C0GENERAL", "reopen "+secret.ID+" looks back")
+	eventually(t, "reply", func() bool { return len(app.slack.Calls("response_url")) == 1 })
+	reply, _ := json.Marshal(app.slack.Calls("response_url")[0].JSON["blocks"])
+	if !strings.Contains(string(reply), "Incident "+secret.ID+" not found.") {
+		t.Errorf("reply = %s", reply)
+	}
+	incident, _ := app.store.GetIncident(ctx, secret.ID)
+	if incident.Status != StatusResolved {
+		t.Errorf("status = %s, want it still resolved", incident.Status)
+	}
+	items, _ := app.store.ListTimelineItems(ctx, secret.ID)
+	for _, item := range items {
+		if strings.Contains(item.Message, "looks back") {
+			t.Errorf("outsider's reason is on the timeline: %q", item.Message)
+		}
+	}
+	entries, _ := app.store.ListAuditEntries(ctx, secret.ID)
+	if last := entries[len(entries)-1]; last.Action != AuditReopen || last.User != "U1" || last.Allowed {
+		t.Errorf("last audit entry = %+v", last)
+	}
+}
+
+func TestReopenArchivedThroughAPI(t *testing.T) {
+	app := newTestApp(t)
+	app.archiveAfter(SeveritySev3, time.Hour)
+	ctx := context.Background()
+
+	var incident Incident
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Search is slow", "severity": "SEV-3"}`, &incident); code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+	if code := app.api(t, http.MethodPost, "/incidents/"+incident.ID+"/resolve", "", nil); code != http.StatusOK {
+		t.Fatalf("resolve: status %d", code)
+	}
+	start := time.Unix(1700000000, 0)
+	for _, now := range []time.Time{start, start.Add(2 * time.Hour)} {
+		if err := app.service.ProcessLifecycle(ctx, now); err != nil {
+			t.Fatalf("ProcessLifecycle: %v", err)
+		}
+	}
+	if !app.slack.Channel(incident.ChannelID).Archived {
+		t.Fatal("channel was not archived")
+	}
+
+	if code := app.api(t, http.MethodPatch, "/incidents/"+incident.ID, `{"status": "Investigating"}`, &incident); code != http.StatusOK {
+		t.Fatalf("patch: status %d", code)
+	}
+	if incident.Status != StatusInvestigating || incident.ArchivedAt != nil || incident.ReopenCount != 1 {
+		t.Errorf("reopened incident = %+v", incident)
+	}
+	if app.slack.Channel(incident.ChannelID).Archived {
+		t.Error("channel is still archived")
+	}
+	if pinned := app.slack.PinnedText(incident.ChannelID); !strings.Contains(pinned, "Incident reopened. Status: Investigating") {
+		t.Errorf("timeline = %s", pinned)
+	}
+}
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	ErrUnknownRole   = errors.New("unknown role")
+	ErrRoleUnchanged = errors.New("role holder unchanged")
+)
+
+// roleInputID is the block and action ID of a role's picker in the update
+// modal.
+func roleInputID(roleID string) string {
+	return "role_" + roleID
+}
+
+// roleChangeField is the history field a role's changes are recorded under.
+// The built-in roles keep the fields they had before roles were configurable.
+func roleChangeField(roleID string) ChangeField {
+	switch roleID {
+	case RoleCommander:
+		return ChangeCommander
+	case RoleComms:
+		return ChangeCommsRep
+	}
+	return ChangeField("role:" + roleID)
+}
+
+// roleChange is one role changing hands. To is empty when the role was
+// unassigned.
+type roleChange struct {
+	Role     RoleDefinition
+	From, To string
+}
+
+func (c roleChange) message() string {
+	if c.To == "" {
+		return fmt.Sprintf("%s removed.", c.Role.Label)
+	}
+	return fmt.Sprintf("%s changed to <@%s>.", c.Role.Label, c.To)
+}
+
+// setRoles assigns the roles in holders, by role ID, leaving roles that
+// aren't in it alone. New holders are added to the incident's members. The
+// changes are returned in taxonomy order.
+func (s *IncidentService) setRoles(incident *Incident, holders map[string]string) ([]roleChange, error) {
+	taxonomy := s.config().Taxonomy
+	for roleID := range holders {
+		if _, ok := taxonomy.Role(roleID); !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownRole, roleID)
+		}
+	}
+
+	var changes []roleChange
+	for _, role := range taxonomy.Roles {
+		userID, ok := holders[role.ID]
+		if !ok || userID == incident.RoleHolder(role.ID) {
+			continue
+		}
+		changes = append(changes, roleChange{Role: role, From: incident.RoleHolder(role.ID), To: userID})
+		incident.setRoleHolder(role.ID, userID)
+		if userID != "" {
+			incident.Members = appendIfMissing(incident.Members, userID)
+		}
+	}
+	return changes, nil
+}
+
+// announceRoleChanges invites new role holders to the incident channel and
+// sends each their role's checklist. The assignment is already stored and
+// shown in the topic, and a missed invite or checklist is easy to make up by
+// hand, so failures are logged and the assignment stands.
+func (s *IncidentService) announceRoleChanges(ctx context.Context, incident *Incident, changes []roleChange) {
+	var invite []string
+	for _, change := range changes {
+		if change.To != "" {
+			invite = appendIfMissing(invite, change.To)
+		}
+	}
+	if len(invite) == 0 {
+		return
+	}
+	err := s.slackService.InviteUsersToChannel(ctx, incident.ChannelID, invite...)
+	if err != nil && !strings.Contains(err.Error(), "already_in_channel") {
+		slog.WarnContext(ctx, "Failed to invite new role holders to channel", "channelID", incident.ChannelID, "users", invite, "error", err)
+	}
+	for _, change := range changes {
+		if change.To != "" {
+			s.sendRoleChecklist(ctx, incident, change.Role, change.To)
+		}
+	}
+}
+
+// sendRoleChecklist DMs a user what they've been made responsible for.
+func (s *IncidentService) sendRoleChecklist(ctx context.Context, incident *Incident, role RoleDefinition, userID string) {
+	_, err := s.slackService.PostMessage(ctx, userID, roleChecklistBlocks(incident, role))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to send role checklist", "incidentID", incident.ID, "role", role.ID, "userID", userID, "error", err)
+	}
+}
+
+func roleChecklistBlocks(incident *Incident, role RoleDefinition) []slack.Block {
+	mrkdwn := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject("mrkdwn", text, false, false)
+	}
+	intro := fmt.Sprintf(":wave: You're now the *%s* for %s in <#%s>: %s", role.Label, incident.ID, incident.ChannelID, incident.Description)
+	if role.Description != "" {
+		intro += "\n" + role.Description
+	}
+	blocks := []slack.Block{slack.NewSectionBlock(mrkdwn(intro), nil, nil)}
+	if len(role.Checklist) > 0 {
+		lines := []string{"*Checklist*"}
+		for _, item := range role.Checklist {
+			lines = append(lines, "• "+item)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(mrkdwn(strings.Join(lines, "\n")), nil, nil))
+	}
+	return blocks
+}
+
+// AssignRole gives a role to a user, or unassigns it if userID is empty. The
+// change is recorded on the timeline and the new holder is invited to the
+// channel and sent the role's checklist. It returns ErrRoleUnchanged if the
+// user already has the role.
+func (s *IncidentService) AssignRole(ctx context.Context, channelID, actorID, roleID, userID string) (*Incident, error) {
+	var assigned *Incident
+	err := s.withIncident(ctx, channelID, func(incident *Incident) error {
+		assigned = incident
+		return s.assignRole(ctx, incident, actorID, roleID, userID, "")
+	})
+	return assigned, err
+}
+
+// assignRole is AssignRole for a caller holding the incident lock. The
+// timeline entry is message, or describes the change if message is empty.
+func (s *IncidentService) assignRole(ctx context.Context, incident *Incident, actorID, roleID, userID, message string) error {
+	before := *incident
+	before.Roles = maps.Clone(incident.Roles)
+	oldTopic := incidentTopic(incident)
+
+	changes, err := s.setRoles(incident, map[string]string{roleID: userID})
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return ErrRoleUnchanged
+	}
+	if err := s.store.UpdateIncident(ctx, incident); err != nil {
+		return fmt.Errorf("failed to store role change: %w", err)
+	}
+	s.recordChanges(ctx, &before, incident, actorID)
+	s.webhooks.Publish(ctx, WebhookPayload{Event: WebhookIncidentUpdated, Incident: incident})
+
+	if newTopic := incidentTopic(incident); newTopic != oldTopic {
+		if err := s.slackService.SetChannelTopic(ctx, incident.ChannelID, newTopic); err != nil {
+			slog.WarnContext(ctx, "Failed to update channel topic", "channelID", incident.ChannelID, "newTopic", newTopic, "error", err)
+		}
+	}
+	s.updateBroadcast(ctx, incident)
+	s.announceRoleChanges(ctx, incident, changes)
+
+	if message == "" {
+		message = changes[0].message()
+	}
+	if err := s.addTimelineItem(ctx, incident, actorID, message); err != nil {
+		return fmt.Errorf("failed to add timeline item: %w", err)
+	}
+	return nil
+}
+
+// RoleListMessage shows who has each role in an incident.
+func (s *IncidentService) RoleListMessage(incident *Incident) []slack.Block {
+	lines := []string{fmt.Sprintf("*Roles in %s*", incident.ID)}
+	for _, role := range s.config().Taxonomy.Roles {
+		holder := "_Unassigned_"
+		if userID := incident.RoleHolder(role.ID); userID != "" {
+			holder = fmt.Sprintf("<@%s>", userID)
+		}
+		lines = append(lines, fmt.Sprintf("• *%s* (`%s`): %s", role.Label, role.ID, holder))
+	}
+	return []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", strings.Join(lines, "\n"), false, false), nil, nil)}
+}
+
+// roleInputs builds the update modal's role pickers, pre-filled with the
+// incident's current holders.
+func (s *IncidentService) roleInputs(incident *Incident) []slack.Block {
+	var blocks []slack.Block
+	for _, role := range s.config().Taxonomy.Roles {
+		element := &slack.SelectBlockElement{
+			Type:        "users_select",
+			Placeholder: slack.NewTextBlockObject("plain_text", "Select "+role.Label, false, false),
+			ActionID:    roleInputID(role.ID),
+		}
+		if incident != nil {
+			element.InitialUser = incident.RoleHolder(role.ID)
+		}
+		var hint *slack.TextBlockObject
+		if role.Description != "" {
+			hint = slack.NewTextBlockObject("plain_text", role.Description, false, false)
+		}
+		input := slack.NewInputBlock(roleInputID(role.ID), slack.NewTextBlockObject("plain_text", role.Label+" (Optional)", false, false), hint, element)
+		input.Optional = true
+		blocks = append(blocks, input)
+	}
+	return blocks
+}
+
+// selectedRoles reads the role pickers of an update modal submission. Roles
+// the modal didn't have, because the taxonomy changed since it was opened,
+// are left out so they stay as they are.
+func (s *IncidentService) selectedRoles(values map[string]map[string]slack.BlockAction) map[string]string {
+	roles := make(map[string]string)
+	for _, role := range s.config().Taxonomy.Roles {
+		if _, ok := values[roleInputID(role.ID)]; ok {
+			roles[role.ID] = selectedUser(values, roleInputID(role.ID))
+		}
+	}
+	return roles
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// directMessages returns the text of the messages HAL sent a user.
+func (a *testApp) directMessages(userID string) []string {
+	var texts []string
+	for _, call := range a.slack.Calls("chat.postMessage") {
+		if call.Form.Get("channel") == userID {
+			texts = append(texts, call.Form.Get("blocks"))
+		}
+	}
+	return texts
+}
+
+func TestRoles(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+
+	app.interaction(t, createIncidentSubmission("V1600", "Emails are delayed", "SEV-2"))
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	incident := app.incident()
+	if dms := app.directMessages("U2"); len(dms) != 1 || !strings.Contains(dms[0], "Incident Commander") {
+		t.Errorf("commander DMs = %v", dms)
+	}
+
+	app.command(t, incident.ChannelID, "role assign tech lead <@U5|erin>")
+	eventually(t, "tech lead assigned", func() bool { return app.incident().Roles[RoleTechLead] == "U5" })
+	eventually(t, "tech lead checklist", func() bool { return len(app.directMessages("U5")) == 1 })
+	if dm := app.directMessages("U5")[0]; !strings.Contains(dm, "Tech Lead") || !strings.Contains(dm, "working theory") {
+		t.Errorf("tech lead DM = %s", dm)
+	}
+	if members := app.slack.Channel(incident.ChannelID).Members; !slices.Contains(members, "U5") {
+		t.Errorf("channel members = %v", members)
+	}
+
+	app.command(t, incident.ChannelID, "role assign ic <@U6>")
+	eventually(t, "commander topic", func() bool {
+		return strings.Contains(app.slack.Channel(incident.ChannelID).Topic, "Commander: <@U6>")
+	})
+	app.command(t, incident.ChannelID, "role assign commander <@U6>")
+	eventually(t, "unchanged reply", func() bool {
+		calls := app.slack.Calls("response_url")
+		return len(calls) == 1 && strings.Contains(calls[0].JSON["blocks"].([]any)[0].(map[string]any)["text"].(map[string]any)["text"].(string), "already the Incident Commander")
+	})
+	app.command(t, incident.ChannelID, "role unassign tech_lead")
+	eventually(t, "tech lead unassigned", func() bool { return app.incident().Roles[RoleTechLead] == "" })
+
+	// The update modal has a picker for every role.
+	var submission slack.InteractionCallback
+	submission.Type = slack.InteractionTypeViewSubmission
+	submission.User = slack.User{ID: "U1"}
+	submission.View = slack.View{
+		ID:              "V1601",
+		CallbackID:      "update_incident_modal",
+		PrivateMetadata: incident.ChannelID,
+		State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+			"status":                   {"status": {SelectedOption: slack.OptionBlockObject{Value: string(StatusFixing)}}},
+			"incident_severity":        {"incident_severity": {SelectedOption: slack.OptionBlockObject{Value: "SEV-2"}}},
+			roleInputID(RoleCommander): {roleInputID(RoleCommander): {SelectedUser: "U6"}},
+			roleInputID(RoleComms):     {roleInputID(RoleComms): {}},
+			roleInputID(RoleScribe):    {roleInputID(RoleScribe): {SelectedUser: "U7"}},
+		}},
+	}
+	app.interaction(t, submission)
+	eventually(t, "scribe checklist", func() bool { return len(app.directMessages("U7")) == 1 })
+
+	incident = app.incident()
+	if incident.Status != StatusFixing || incident.CommanderID != "U6" || incident.Roles[RoleScribe] != "U7" {
+		t.Errorf("updated incident = %+v", incident)
+	}
+
+	// The API takes any role.
+	if code := app.api(t, http.MethodPatch, "/incidents/"+incident.ID, `{"roles": {"comms": "U8"}}`, nil); code != http.StatusOK {
+		t.Errorf("patch roles: status %d", code)
+	}
+	if code := app.api(t, http.MethodPatch, "/incidents/"+incident.ID, `{"roles": {"janitor": "U8"}}`, nil); code != http.StatusBadRequest {
+		t.Errorf("patch unknown role: status %d", code)
+	}
+
+	var timeline []string
+	items, _ := app.store.ListTimelineItems(ctx, incident.ID)
+	for _, item := range items {
+		timeline = append(timeline, item.Message)
+	}
+	for _, want := range []string{
+		"Tech Lead changed to <@U5>.",
+		"Incident Commander changed to <@U6>.",
+		"Tech Lead removed.",
+		"Incident updated. Status: Fixing, Severity: SEV-2 Scribe changed to <@U7>.",
+		"Incident updated. Status: Fixing, Severity: SEV-2 Comms Representative changed to <@U8>.",
+	} {
+		if !slices.Contains(timeline, want) {
+			t.Errorf("timeline missing %q: %q", want, timeline)
+		}
+	}
+
+	changes, _ := app.store.ListIncidentChanges(ctx, incident.ID)
+	var fields []ChangeField
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	for _, want := range []ChangeField{"role:tech_lead", ChangeCommander, "role:scribe", ChangeCommsRep} {
+		if !slices.Contains(fields, want) {
+			t.Errorf("changes missing %s: %v", want, fields)
+		}
+	}
+}
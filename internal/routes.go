@@ -2,13 +2,40 @@ package internal
 
 import "github.com/gin-gonic/gin"
 
-func RegisterRoutes(router *gin.Engine, incidentService *IncidentService, slackService *SlackService) {
-	router.Use(func(c *gin.Context) {
-		c.Set("slackApi", slackService.GetClient())
-		c.Next()
-	})
-
+// RegisterRoutes registers HAL's HTTP routes. In Socket Mode Slack requests
+// arrive over the socket, so the Slack endpoints are left out. The
+// REST API and the Alertmanager webhook are only registered when they have a
+// token.
+func RegisterRoutes(router *gin.Engine, incidentService *IncidentService, slackService *SlackService, queue *WorkQueue, config *Config) {
 	router.GET("/health", HealthHandler(slackService))
-	router.POST("/incident", IncidentHandler(incidentService))
-	router.POST("/interaction", InteractionHandler(incidentService))
+
+	if config.APIToken != "" {
+		api := router.Group("/api/v1", BearerAuthMiddleware(config.APIToken))
+		api.POST("/incidents", CreateIncidentAPIHandler(incidentService))
+		api.GET("/incidents", ListIncidentsAPIHandler(incidentService))
+		api.GET("/incidents/:id", GetIncidentAPIHandler(incidentService))
+		api.PATCH("/incidents/:id", UpdateIncidentAPIHandler(incidentService))
+		api.POST("/incidents/:id/resolve", ResolveIncidentAPIHandler(incidentService))
+		api.GET("/incidents/:id/timeline", ListTimelineAPIHandler(incidentService))
+		api.POST("/incidents/:id/timeline", AddTimelineAPIHandler(incidentService))
+		api.GET("/incidents/:id/action-items", ListActionItemsAPIHandler(incidentService))
+		api.POST("/incidents/:id/action-items", AddActionItemAPIHandler(incidentService))
+		api.PATCH("/incidents/:id/action-items/:number", UpdateActionItemAPIHandler(incidentService))
+		api.GET("/incidents/:id/postmortem", PostmortemAPIHandler(incidentService))
+		api.GET("/incidents/:id/audit", AuditLogAPIHandler(incidentService))
+		api.GET("/stats", StatsAPIHandler(incidentService))
+	}
+
+	if config.Alertmanager.Token != "" {
+		router.POST("/webhooks/alertmanager", BearerAuthMiddleware(config.Alertmanager.Token), AlertmanagerHandler(incidentService, queue))
+	}
+
+	if config.SocketMode {
+		return
+	}
+
+	slackRoutes := router.Group("/", SlackAuthMiddleware(config.SlackSigningSecret))
+	slackRoutes.POST("/incident", IncidentHandler(incidentService, queue))
+	slackRoutes.POST("/interaction", InteractionHandler(incidentService, queue))
+	slackRoutes.POST("/events", EventHandler(incidentService, queue))
 }
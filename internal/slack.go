@@ -9,24 +9,41 @@ import (
 	"github.com/slack-go/slack"
 )
 
+// SlackAPI is the subset of the Slack Web API that HAL uses. *slack.Client
+// implements it; tests point a real client at a fake server instead.
+type SlackAPI interface {
+	CreateConversationContext(ctx context.Context, params slack.CreateConversationParams) (*slack.Channel, error)
+	InviteUsersToConversationContext(ctx context.Context, channelID string, users ...string) (*slack.Channel, error)
+	SetTopicOfConversationContext(ctx context.Context, channelID, topic string) (*slack.Channel, error)
+	GetConversationInfoContext(ctx context.Context, input *slack.GetConversationInfoInput) (*slack.Channel, error)
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error)
+	UpdateMessageContext(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	AddPinContext(ctx context.Context, channel string, item slack.ItemRef) error
+	ListPinsContext(ctx context.Context, channel string) ([]slack.Item, *slack.Paging, error)
+	OpenViewContext(ctx context.Context, triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error)
+	ArchiveConversationContext(ctx context.Context, channelID string) error
+	UnArchiveConversationContext(ctx context.Context, channelID string) error
+	GetReactionsContext(ctx context.Context, item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error)
+	PublishViewContext(ctx context.Context, userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error)
+	UploadFileV2Context(ctx context.Context, params slack.UploadFileV2Parameters) (*slack.FileSummary, error)
+	AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error)
+}
+
 type SlackService struct {
-	client *slack.Client
+	client SlackAPI
 	config *Config
 }
 
-func NewSlackService(client *slack.Client, config *Config) *SlackService {
+func NewSlackService(client SlackAPI, config *Config) *SlackService {
 	return &SlackService{
 		client: client,
 		config: config,
 	}
 }
 
-func (s *SlackService) GetClient() *slack.Client {
-	return s.client
-}
-
 func (s *SlackService) CreateChannel(ctx context.Context, name string, isPrivate bool) (*slack.Channel, error) {
-	channel, err := s.client.CreateConversation(slack.CreateConversationParams{
+	channel, err := s.client.CreateConversationContext(ctx, slack.CreateConversationParams{
 		ChannelName: name,
 		IsPrivate:   isPrivate,
 	})
@@ -42,7 +59,7 @@ func (s *SlackService) InviteUsersToChannel(ctx context.Context, channelID strin
 		return nil
 	}
 
-	_, err := s.client.InviteUsersToConversation(channelID, userIDs...)
+	_, err := s.client.InviteUsersToConversationContext(ctx, channelID, userIDs...)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to invite users to Slack channel", "error", err)
 		return fmt.Errorf("failed to invite users to Slack channel: %w", err)
@@ -51,7 +68,7 @@ func (s *SlackService) InviteUsersToChannel(ctx context.Context, channelID strin
 }
 
 func (s *SlackService) SetChannelTopic(ctx context.Context, channelID, topic string) error {
-	_, err := s.client.SetTopicOfConversation(channelID, topic)
+	_, err := s.client.SetTopicOfConversationContext(ctx, channelID, topic)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to set Slack channel topic", "error", err)
 		return fmt.Errorf("failed to set Slack channel topic: %w", err)
@@ -60,7 +77,8 @@ func (s *SlackService) SetChannelTopic(ctx context.Context, channelID, topic str
 }
 
 func (s *SlackService) PostMessage(ctx context.Context, channelID string, blocks []slack.Block) (string, error) {
-	_, timestamp, err := s.client.PostMessage(
+	_, timestamp, err := s.client.PostMessageContext(
+		ctx,
 		channelID,
 		slack.MsgOptionBlocks(blocks...),
 		slack.MsgOptionAsUser(true),
@@ -72,8 +90,42 @@ func (s *SlackService) PostMessage(ctx context.Context, channelID string, blocks
 	return timestamp, nil
 }
 
+// PostAttachment posts blocks inside an attachment, which Slack renders with
+// a coloured bar down the side. channel may be a name; the returned channel
+// ID is the one Slack resolved it to.
+func (s *SlackService) PostAttachment(ctx context.Context, channel, color string, blocks []slack.Block) (string, string, error) {
+	channelID, timestamp, err := s.client.PostMessageContext(
+		ctx,
+		channel,
+		slack.MsgOptionAttachments(slack.Attachment{Color: color, Blocks: slack.Blocks{BlockSet: blocks}}),
+		slack.MsgOptionAsUser(true),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to post attachment to Slack channel", "error", err)
+		return "", "", fmt.Errorf("failed to post attachment to Slack channel: %w", err)
+	}
+	return channelID, timestamp, nil
+}
+
+// UpdateAttachment replaces a message posted with PostAttachment.
+func (s *SlackService) UpdateAttachment(ctx context.Context, channelID, timestamp, color string, blocks []slack.Block) error {
+	_, _, _, err := s.client.UpdateMessageContext(
+		ctx,
+		channelID,
+		timestamp,
+		slack.MsgOptionAttachments(slack.Attachment{Color: color, Blocks: slack.Blocks{BlockSet: blocks}}),
+		slack.MsgOptionAsUser(true),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update attachment", "error", err)
+		return fmt.Errorf("failed to update attachment: %w", err)
+	}
+	return nil
+}
+
 func (s *SlackService) PostEphemeralMessage(ctx context.Context, channelID, userID string, blocks []slack.Block) error {
-	_, err := s.client.PostEphemeral(
+	_, err := s.client.PostEphemeralContext(
+		ctx,
 		channelID,
 		userID,
 		slack.MsgOptionBlocks(blocks...),
@@ -85,8 +137,52 @@ func (s *SlackService) PostEphemeralMessage(ctx context.Context, channelID, user
 	return nil
 }
 
+// ResponseTarget identifies who to answer for a command or interaction.
+type ResponseTarget struct {
+	ResponseURL string
+	ChannelID   string
+	UserID      string
+}
+
+// Respond sends an ephemeral reply to the user behind a command or
+// interaction. It prefers the response URL, which works even in channels HAL
+// hasn't joined, then an ephemeral message in the channel, then a DM.
+func (s *SlackService) Respond(ctx context.Context, target ResponseTarget, blocks ...slack.Block) error {
+	if target.ResponseURL != "" {
+		_, _, err := s.client.PostMessageContext(
+			ctx,
+			target.ChannelID,
+			slack.MsgOptionBlocks(blocks...),
+			slack.MsgOptionResponseURL(target.ResponseURL, slack.ResponseTypeEphemeral),
+		)
+		if err == nil {
+			return nil
+		}
+		slog.WarnContext(ctx, "Failed to respond via response URL, falling back", "error", err)
+	}
+
+	if target.ChannelID != "" {
+		err := s.PostEphemeralMessage(ctx, target.ChannelID, target.UserID, blocks)
+		if err == nil {
+			return nil
+		}
+	}
+
+	if target.UserID == "" {
+		return fmt.Errorf("no way to respond: missing response URL, channel and user")
+	}
+	_, err := s.PostMessage(ctx, target.UserID, blocks)
+	return err
+}
+
+// RespondText is Respond for a single mrkdwn text section.
+func (s *SlackService) RespondText(ctx context.Context, target ResponseTarget, text string) error {
+	return s.Respond(ctx, target, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil))
+}
+
 func (s *SlackService) UpdateMessage(ctx context.Context, channelID, timestamp string, blocks []slack.Block) error {
-	_, _, _, err := s.client.UpdateMessage(
+	_, _, _, err := s.client.UpdateMessageContext(
+		ctx,
 		channelID,
 		timestamp,
 		slack.MsgOptionBlocks(blocks...),
@@ -100,7 +196,7 @@ func (s *SlackService) UpdateMessage(ctx context.Context, channelID, timestamp s
 }
 
 func (s *SlackService) AddPin(ctx context.Context, channelID, timestamp string) error {
-	err := s.client.AddPin(channelID, slack.ItemRef{
+	err := s.client.AddPinContext(ctx, channelID, slack.ItemRef{
 		Channel:   channelID,
 		Timestamp: timestamp,
 	})
@@ -112,7 +208,7 @@ func (s *SlackService) AddPin(ctx context.Context, channelID, timestamp string)
 }
 
 func (s *SlackService) ListPins(ctx context.Context, channelID string) ([]slack.Item, error) {
-	items, _, err := s.client.ListPins(channelID)
+	items, _, err := s.client.ListPinsContext(ctx, channelID)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to list pins", "error", err)
 		return nil, fmt.Errorf("failed to list pins: %w", err)
@@ -121,7 +217,7 @@ func (s *SlackService) ListPins(ctx context.Context, channelID string) ([]slack.
 }
 
 func (s *SlackService) GetChannelTopic(ctx context.Context, channelID string) (string, error) {
-	info, err := s.client.GetConversationInfo(&slack.GetConversationInfoInput{ChannelID: channelID, IncludeLocale: false, IncludeNumMembers: false})
+	info, err := s.client.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channelID, IncludeLocale: false, IncludeNumMembers: false})
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to get conversation info", "channelID", channelID, "error", err)
 		return "", fmt.Errorf("failed to get conversation info for channel %s: %w", channelID, err)
@@ -134,7 +230,7 @@ func (s *SlackService) GetChannelTopic(ctx context.Context, channelID string) (s
 }
 
 func (s *SlackService) OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error {
-	_, err := s.client.OpenView(triggerID, view)
+	_, err := s.client.OpenViewContext(ctx, triggerID, view)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to open modal view", "error", err)
 		return fmt.Errorf("failed to open modal view: %w", err)
@@ -142,6 +238,60 @@ func (s *SlackService) OpenView(ctx context.Context, triggerID string, view slac
 	return nil
 }
 
+func (s *SlackService) PublishHomeView(ctx context.Context, userID string, view slack.HomeTabViewRequest) error {
+	_, err := s.client.PublishViewContext(ctx, userID, view, "")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to publish home view", "userID", userID, "error", err)
+		return fmt.Errorf("failed to publish home view: %w", err)
+	}
+	return nil
+}
+
+func (s *SlackService) ArchiveChannel(ctx context.Context, channelID string) error {
+	err := s.client.ArchiveConversationContext(ctx, channelID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to archive channel", "channelID", channelID, "error", err)
+		return fmt.Errorf("failed to archive channel: %w", err)
+	}
+	return nil
+}
+
+func (s *SlackService) UnarchiveChannel(ctx context.Context, channelID string) error {
+	err := s.client.UnArchiveConversationContext(ctx, channelID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to unarchive channel", "channelID", channelID, "error", err)
+		return fmt.Errorf("failed to unarchive channel: %w", err)
+	}
+	return nil
+}
+
+// UploadFile shares a text file in a channel with comment as its message.
+func (s *SlackService) UploadFile(ctx context.Context, channelID, filename, title, content, comment string) error {
+	_, err := s.client.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Channel:        channelID,
+		Filename:       filename,
+		Title:          title,
+		Content:        content,
+		FileSize:       len(content),
+		InitialComment: comment,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to upload file", "channelID", channelID, "filename", filename, "error", err)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	return nil
+}
+
+// GetReactions returns the reactions on a message.
+func (s *SlackService) GetReactions(ctx context.Context, channelID, timestamp string) ([]slack.ItemReaction, error) {
+	reactions, err := s.client.GetReactionsContext(ctx, slack.ItemRef{Channel: channelID, Timestamp: timestamp}, slack.NewGetReactionsParameters())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get reactions", "channelID", channelID, "timestamp", timestamp, "error", err)
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+	return reactions, nil
+}
+
 func (s *SlackService) ValidateSlackRequest(signature, timestamp, body string) bool {
 	if signature == "" || timestamp == "" || body == "" {
 		return false
@@ -150,7 +300,7 @@ func (s *SlackService) ValidateSlackRequest(signature, timestamp, body string) b
 }
 
 func (s *SlackService) HealthCheck(ctx context.Context) error {
-	_, err := s.client.AuthTest()
+	_, err := s.client.AuthTestContext(ctx)
 	if err != nil {
 		return errors.New("slack API is unavailable")
 	}
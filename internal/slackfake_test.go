@@ -0,0 +1,353 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeSlack is an in-process stand-in for the Slack Web API. Point a real
+// *slack.Client at it with newClient; it records every call and keeps just
+// enough state (channels, topics, messages, pins) for HAL's flows.
+type fakeSlack struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	calls    []fakeSlackCall
+	channels map[string]*fakeChannel
+	nextID   int
+	// failures makes calls to a method fail with the given error.
+	failures map[string]string
+}
+
+type fakeSlackCall struct {
+	Method string
+	Form   url.Values
+	JSON   map[string]any
+}
+
+type fakeChannel struct {
+	ID       string
+	Name     string
+	Private  bool
+	Archived bool
+	Topic    string
+	Members  []string
+	Messages []*fakeMessage
+}
+
+type fakeMessage struct {
+	TS        string
+	Blocks    json.RawMessage
+	Pinned    bool
+	Reactions []string
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{channels: make(map[string]*fakeChannel), failures: make(map[string]string)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeSlack) newClient() *slack.Client {
+	return slack.New("xoxb-test", slack.OptionAPIURL(f.server.URL+"/api/"))
+}
+
+// responseURL returns a URL HAL can post command responses to; they are
+// recorded as "response_url" calls.
+func (f *fakeSlack) responseURL() string {
+	return f.server.URL + "/response"
+}
+
+func (f *fakeSlack) serve(w http.ResponseWriter, r *http.Request) {
+	call := fakeSlackCall{Method: strings.TrimPrefix(r.URL.Path, "/api/")}
+	if r.URL.Path == "/response" {
+		call.Method = "response_url"
+	}
+	// File contents are posted to the URL from files.getUploadURLExternal.
+	if strings.HasPrefix(r.URL.Path, "/upload/") {
+		call.Method = "file_upload"
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		_ = json.Unmarshal(body, &call.JSON)
+	} else {
+		call.Form, _ = url.ParseQuery(string(body))
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	resp := f.handle(call)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handle produces the API response for a call. Callers must hold f.mu.
+func (f *fakeSlack) handle(call fakeSlackCall) map[string]any {
+	ok := map[string]any{"ok": true}
+	fail := func(err string) map[string]any { return map[string]any{"ok": false, "error": err} }
+	if err, found := f.failures[call.Method]; found {
+		return fail(err)
+	}
+
+	switch call.Method {
+	case "conversations.create":
+		name := call.Form.Get("name")
+		for _, ch := range f.channels {
+			if ch.Name == name {
+				return fail("name_taken")
+			}
+		}
+		f.nextID++
+		ch := &fakeChannel{ID: fmt.Sprintf("C%03d", f.nextID), Name: name, Private: call.Form.Get("is_private") == "true"}
+		f.channels[ch.ID] = ch
+		return map[string]any{"ok": true, "channel": f.channelJSON(ch)}
+
+	case "conversations.invite":
+		ch, found := f.channels[call.Form.Get("channel")]
+		if !found {
+			return fail("channel_not_found")
+		}
+		ch.Members = append(ch.Members, strings.Split(call.Form.Get("users"), ",")...)
+		return map[string]any{"ok": true, "channel": f.channelJSON(ch)}
+
+	case "conversations.setTopic":
+		ch, found := f.channels[call.Form.Get("channel")]
+		if !found {
+			return fail("channel_not_found")
+		}
+		if ch.Archived {
+			return fail("is_archived")
+		}
+		ch.Topic = call.Form.Get("topic")
+		return map[string]any{"ok": true, "channel": f.channelJSON(ch)}
+
+	case "conversations.archive":
+		ch, found := f.channels[call.Form.Get("channel")]
+		if !found {
+			return fail("channel_not_found")
+		}
+		if ch.Archived {
+			return fail("already_archived")
+		}
+		ch.Archived = true
+		return ok
+
+	case "conversations.unarchive":
+		ch, found := f.channels[call.Form.Get("channel")]
+		if !found {
+			return fail("channel_not_found")
+		}
+		if !ch.Archived {
+			return fail("not_archived")
+		}
+		ch.Archived = false
+		return ok
+
+	case "conversations.info":
+		ch, found := f.channels[call.Form.Get("channel")]
+		if !found {
+			return fail("channel_not_found")
+		}
+		return map[string]any{"ok": true, "channel": f.channelJSON(ch)}
+
+	case "chat.postMessage":
+		channelID := call.Form.Get("channel")
+		ch, found := f.channels[channelID]
+		if !found {
+			// Messages to users land in an implicit DM channel.
+			ch = &fakeChannel{ID: channelID}
+			f.channels[channelID] = ch
+		}
+		if ch.Archived {
+			return fail("is_archived")
+		}
+		f.nextID++
+		msg := &fakeMessage{TS: fmt.Sprintf("1700000000.%06d", f.nextID), Blocks: json.RawMessage(call.Form.Get("blocks"))}
+		ch.Messages = append(ch.Messages, msg)
+		return map[string]any{"ok": true, "channel": channelID, "ts": msg.TS}
+
+	case "chat.update":
+		msg := f.message(call.Form.Get("channel"), call.Form.Get("ts"))
+		if msg == nil {
+			return fail("message_not_found")
+		}
+		msg.Blocks = json.RawMessage(call.Form.Get("blocks"))
+		return map[string]any{"ok": true, "channel": call.Form.Get("channel"), "ts": msg.TS}
+
+	case "chat.postEphemeral":
+		f.nextID++
+		return map[string]any{"ok": true, "message_ts": fmt.Sprintf("1700000000.%06d", f.nextID)}
+
+	case "pins.add":
+		msg := f.message(call.Form.Get("channel"), call.Form.Get("timestamp"))
+		if msg == nil {
+			return fail("message_not_found")
+		}
+		if msg.Pinned {
+			return fail("already_pinned")
+		}
+		msg.Pinned = true
+		return ok
+
+	case "pins.list":
+		var items []map[string]any
+		if ch, found := f.channels[call.Form.Get("channel")]; found {
+			for _, msg := range ch.Messages {
+				if msg.Pinned {
+					items = append(items, map[string]any{
+						"type":    "message",
+						"channel": ch.ID,
+						"message": map[string]any{"ts": msg.TS, "blocks": msg.Blocks},
+					})
+				}
+			}
+		}
+		return map[string]any{"ok": true, "items": items}
+
+	case "reactions.get":
+		msg := f.message(call.Form.Get("channel"), call.Form.Get("timestamp"))
+		if msg == nil {
+			return fail("message_not_found")
+		}
+		var reactions []map[string]any
+		for _, name := range msg.Reactions {
+			reactions = append(reactions, map[string]any{"name": name, "count": 1, "users": []string{"U1"}})
+		}
+		return map[string]any{"ok": true, "type": "message", "channel": call.Form.Get("channel"),
+			"message": map[string]any{"ts": msg.TS, "reactions": reactions}}
+
+	case "views.open":
+		f.nextID++
+		return map[string]any{"ok": true, "view": map[string]any{"id": fmt.Sprintf("V%03d", f.nextID)}}
+
+	case "views.publish":
+		f.nextID++
+		return map[string]any{"ok": true, "view": map[string]any{"id": fmt.Sprintf("V%03d", f.nextID)}}
+
+	case "files.getUploadURLExternal":
+		f.nextID++
+		fileID := fmt.Sprintf("F%03d", f.nextID)
+		return map[string]any{"ok": true, "file_id": fileID, "upload_url": f.server.URL + "/upload/" + fileID}
+
+	case "files.completeUploadExternal":
+		var files []map[string]any
+		_ = json.Unmarshal([]byte(call.Form.Get("files")), &files)
+		return map[string]any{"ok": true, "files": files}
+
+	case "auth.test":
+		return map[string]any{"ok": true, "user_id": "UHAL", "bot_id": "BHAL"}
+	}
+
+	return ok
+}
+
+func (f *fakeSlack) channelJSON(ch *fakeChannel) map[string]any {
+	return map[string]any{
+		"id":         ch.ID,
+		"name":       ch.Name,
+		"is_private": ch.Private,
+		"topic":      map[string]any{"value": ch.Topic},
+	}
+}
+
+func (f *fakeSlack) message(channelID, ts string) *fakeMessage {
+	ch, found := f.channels[channelID]
+	if !found {
+		return nil
+	}
+	for _, msg := range ch.Messages {
+		if msg.TS == ts {
+			return msg
+		}
+	}
+	return nil
+}
+
+// Fail makes every later call to method fail with err.
+func (f *fakeSlack) Fail(method, err string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = err
+}
+
+// React adds an emoji reaction to a message.
+func (f *fakeSlack) React(channelID, ts, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if msg := f.message(channelID, ts); msg != nil {
+		msg.Reactions = append(msg.Reactions, name)
+	}
+}
+
+// Calls returns the recorded calls to method.
+func (f *fakeSlack) Calls(method string) []fakeSlackCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []fakeSlackCall
+	for _, call := range f.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Channel returns a copy of a channel's state.
+func (f *fakeSlack) Channel(channelID string) fakeChannel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch, found := f.channels[channelID]
+	if !found {
+		return fakeChannel{}
+	}
+	return *ch
+}
+
+// Messages returns copies of the channel's messages, which unlike those of
+// Channel are safe to read while HAL edits them.
+func (f *fakeSlack) Messages(channelID string) []fakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var messages []fakeMessage
+	if ch, found := f.channels[channelID]; found {
+		for _, msg := range ch.Messages {
+			messages = append(messages, *msg)
+		}
+	}
+	return messages
+}
+
+// PinnedText returns the concatenated block text of the channel's pinned
+// messages.
+func (f *fakeSlack) PinnedText(channelID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var text strings.Builder
+	if ch, found := f.channels[channelID]; found {
+		for _, msg := range ch.Messages {
+			if msg.Pinned {
+				text.Write(msg.Blocks)
+			}
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// socketModeAcker is the part of *socketmode.Client the dispatcher needs.
+type socketModeAcker interface {
+	Ack(req socketmode.Request, payload ...interface{})
+}
+
+// RunSocketMode receives slash commands, interactions and events over a Slack
+// Socket Mode connection instead of the public HTTP endpoints, and runs them
+// through the same service logic and work queue. It returns when ctx is
+// cancelled or the connection can't be re-established.
+func RunSocketMode(ctx context.Context, client *socketmode.Client, incidentService *IncidentService, queue *WorkQueue) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- client.RunContext(ctx)
+	}()
+
+	for {
+		select {
+		case err := <-errc:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("socket mode connection failed: %w", err)
+		case evt := <-client.Events:
+			handleSocketModeEvent(ctx, client, incidentService, queue, evt)
+		}
+	}
+}
+
+func handleSocketModeEvent(ctx context.Context, client socketModeAcker, incidentService *IncidentService, queue *WorkQueue, evt socketmode.Event) {
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		slog.InfoContext(ctx, "Connecting to Slack with Socket Mode")
+	case socketmode.EventTypeConnected:
+		slog.InfoContext(ctx, "Connected to Slack with Socket Mode")
+	case socketmode.EventTypeConnectionError:
+		slog.WarnContext(ctx, "Socket Mode connection failed, retrying", "error", evt.Data)
+
+	case socketmode.EventTypeSlashCommand:
+		var req SlackCommandRequest
+		if err := json.Unmarshal(evt.Request.Payload, &req); err != nil {
+			slog.ErrorContext(ctx, "Failed to parse slash command", "error", err)
+			client.Ack(*evt.Request)
+			return
+		}
+
+		// As over HTTP, modals must be opened while the trigger ID is valid.
+		if commandOpensModal(req.Text) {
+			if err := incidentService.HandleCommand(ctx, req); err != nil {
+				slog.ErrorContext(ctx, "Failed to handle command", "text", req.Text, "error", err)
+				client.Ack(*evt.Request, map[string]any{"text": fmt.Sprintf(":warning: Could not open dialog: %s", err)})
+				return
+			}
+			client.Ack(*evt.Request)
+			return
+		}
+		enqueueSocketMode(ctx, client, queue, evt.Request, "command "+req.Text, commandJob(incidentService, req))
+
+	case socketmode.EventTypeInteractive:
+		interaction, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			slog.ErrorContext(ctx, "Unexpected interactive payload", "data", evt.Data)
+			client.Ack(*evt.Request)
+			return
+		}
+
+		slog.InfoContext(ctx, "Received interaction",
+			"type", interaction.Type,
+			"callbackID", interaction.View.CallbackID,
+			"userID", interaction.User.ID)
+		if interactionOpensModal(interaction) {
+			if err := incidentService.HandleInteraction(ctx, interaction); err != nil {
+				slog.ErrorContext(ctx, "Failed to handle interaction", "error", err)
+			}
+			client.Ack(*evt.Request)
+			return
+		}
+		enqueueSocketMode(ctx, client, queue, evt.Request, interactionJobName(interaction), interactionJob(incidentService, interaction))
+
+	case socketmode.EventTypeEventsAPI:
+		event, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok || event.Type != slackevents.CallbackEvent {
+			client.Ack(*evt.Request)
+			return
+		}
+		callback, ok := event.Data.(*slackevents.EventsAPICallbackEvent)
+		if !ok {
+			client.Ack(*evt.Request)
+			return
+		}
+
+		slog.InfoContext(ctx, "Received event",
+			"type", event.InnerEvent.Type,
+			"eventID", callback.EventID)
+		enqueueSocketMode(ctx, client, queue, evt.Request, "event "+event.InnerEvent.Type, eventJob(incidentService, callback.EventID, event.InnerEvent))
+
+	case socketmode.EventTypeErrorBadMessage:
+		slog.WarnContext(ctx, "Ignoring unparseable Socket Mode message", "error", evt.Data)
+
+	default:
+		slog.DebugContext(ctx, "Ignoring Socket Mode event", "type", evt.Type)
+	}
+}
+
+// enqueueSocketMode queues fn and acknowledges the request. If the queue is
+// full the request is left unacknowledged, which makes Slack retry it or tell
+// the user, like the HTTP handlers' 503.
+func enqueueSocketMode(ctx context.Context, client socketModeAcker, queue *WorkQueue, req *socketmode.Request, name string, fn func(ctx context.Context) error) {
+	if req.RetryAttempt > 0 {
+		name += fmt.Sprintf(" (retry %d)", req.RetryAttempt)
+	}
+	if err := queue.Enqueue(name, fn); err != nil {
+		slog.ErrorContext(ctx, "Failed to queue Slack request", "job", name, "error", err)
+		return
+	}
+	client.Ack(*req)
+}
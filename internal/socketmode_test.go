@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+type recordingAcker struct {
+	mu   sync.Mutex
+	acks map[string][]interface{}
+}
+
+func (a *recordingAcker) Ack(req socketmode.Request, payload ...interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.acks == nil {
+		a.acks = make(map[string][]interface{})
+	}
+	a.acks[req.EnvelopeID] = payload
+}
+
+func (a *recordingAcker) acked(envelopeID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.acks[envelopeID]
+	return ok
+}
+
+func TestSocketModeDispatch(t *testing.T) {
+	app := newTestApp(t)
+	acker := &recordingAcker{}
+	ctx := context.Background()
+
+	dispatch := func(envelopeID string, eventType socketmode.EventType, data interface{}, payload any) {
+		t.Helper()
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("marshal payload: %v", err)
+		}
+		req := &socketmode.Request{EnvelopeID: envelopeID, Payload: raw}
+		handleSocketModeEvent(ctx, acker, app.service, app.queue, socketmode.Event{Type: eventType, Data: data, Request: req})
+		if !acker.acked(envelopeID) {
+			t.Fatalf("envelope %s was not acknowledged", envelopeID)
+		}
+	}
+
+	submission := createIncidentSubmission("V500", "Payments failing", "SEV-2")
+	dispatch("E1", socketmode.EventTypeInteractive, submission, submission)
+	eventually(t, "incident setup", func() bool {
+		return len(app.slack.Calls("pins.add")) == 2
+	})
+	incident := app.incident()
+
+	dispatch("E2", socketmode.EventTypeSlashCommand, nil, map[string]string{
+		"command":    "/incident",
+		"text":       "t failover started",
+		"channel_id": incident.ChannelID,
+		"user_id":    "U1",
+		"trigger_id": "T2",
+	})
+	eventually(t, "timeline entry from slash command", func() bool {
+		items, _ := app.store.ListTimelineItems(ctx, incident.ID)
+		return len(items) == 2 && items[1].Message == "failover started"
+	})
+
+	mention := &slackevents.AppMentionEvent{Type: "app_mention", User: "U1", Channel: incident.ChannelID, Text: "<@UHAL> t failover done"}
+	event := slackevents.EventsAPIEvent{
+		Type:       slackevents.CallbackEvent,
+		Data:       &slackevents.EventsAPICallbackEvent{EventID: "Ev1"},
+		InnerEvent: slackevents.EventsAPIInnerEvent{Type: "app_mention", Data: mention},
+	}
+	dispatch("E3", socketmode.EventTypeEventsAPI, event, nil)
+	eventually(t, "timeline entry from mention", func() bool {
+		items, _ := app.store.ListTimelineItems(ctx, incident.ID)
+		return len(items) == 3 && items[2].Message == "failover done"
+	})
+
+	dispatch("E4", socketmode.EventTypeSlashCommand, nil, map[string]string{
+		"text":       "create",
+		"channel_id": "CGENERAL",
+		"user_id":    "U1",
+		"trigger_id": "T4",
+	})
+	if opened := app.slack.Calls("views.open"); len(opened) != 1 {
+		t.Fatalf("views.open calls = %d, want 1", len(opened))
+	}
+}
@@ -0,0 +1,753 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// migrations are applied in order and tracked with SQLite's user_version
+// pragma. Only ever append to this list.
+var migrations = []string{
+	`CREATE TABLE incidents (
+		number       INTEGER PRIMARY KEY AUTOINCREMENT,
+		id           TEXT UNIQUE,
+		description  TEXT NOT NULL,
+		status       TEXT NOT NULL,
+		severity     TEXT NOT NULL,
+		commander_id TEXT NOT NULL DEFAULT '',
+		comms_rep_id TEXT NOT NULL DEFAULT '',
+		created_by   TEXT NOT NULL DEFAULT '',
+		created_at   TIMESTAMP NOT NULL,
+		updated_at   TIMESTAMP NOT NULL,
+		resolved_at  TIMESTAMP,
+		channel_id   TEXT NOT NULL DEFAULT '',
+		channel_name TEXT NOT NULL DEFAULT '',
+		members      TEXT NOT NULL DEFAULT '[]'
+	);
+	CREATE INDEX incidents_channel_id ON incidents (channel_id);`,
+	`ALTER TABLE incidents ADD COLUMN timeline_ts TEXT NOT NULL DEFAULT '';
+	CREATE TABLE timeline_items (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		incident_id TEXT NOT NULL REFERENCES incidents (id),
+		user_id     TEXT NOT NULL DEFAULT '',
+		message     TEXT NOT NULL,
+		created_at  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX timeline_items_incident_id ON timeline_items (incident_id);`,
+	`ALTER TABLE incidents ADD COLUMN action_items_ts TEXT NOT NULL DEFAULT '';
+	CREATE TABLE action_items (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		incident_id  TEXT NOT NULL REFERENCES incidents (id),
+		number       INTEGER NOT NULL,
+		description  TEXT NOT NULL,
+		user_id      TEXT NOT NULL DEFAULT '',
+		completed    BOOLEAN NOT NULL DEFAULT FALSE,
+		completed_by TEXT NOT NULL DEFAULT '',
+		completed_at TIMESTAMP,
+		created_at   TIMESTAMP NOT NULL,
+		UNIQUE (incident_id, number)
+	);`,
+	`ALTER TABLE incidents ADD COLUMN archived_at TIMESTAMP;`,
+	`ALTER TABLE incidents ADD COLUMN wrap_up_ts TEXT NOT NULL DEFAULT '';
+	ALTER TABLE incidents ADD COLUMN keep_channel BOOLEAN NOT NULL DEFAULT FALSE;`,
+	`ALTER TABLE incidents ADD COLUMN previous_status TEXT NOT NULL DEFAULT '';
+	ALTER TABLE incidents ADD COLUMN reopen_count INTEGER NOT NULL DEFAULT 0;`,
+	`CREATE TABLE webhook_dead_letters (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		delivery_id TEXT NOT NULL,
+		url         TEXT NOT NULL,
+		event       TEXT NOT NULL,
+		payload     TEXT NOT NULL,
+		attempts    INTEGER NOT NULL,
+		last_error  TEXT NOT NULL,
+		created_at  TIMESTAMP NOT NULL
+	);`,
+	`CREATE TABLE alerts (
+		fingerprint TEXT PRIMARY KEY,
+		incident_id TEXT NOT NULL REFERENCES incidents (id),
+		group_key   TEXT NOT NULL DEFAULT '',
+		name        TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL,
+		starts_at   TIMESTAMP NOT NULL,
+		updated_at  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX alerts_incident_id ON alerts (incident_id);
+	CREATE INDEX alerts_group_key ON alerts (group_key);`,
+	`CREATE TABLE incident_changes (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		incident_id TEXT NOT NULL REFERENCES incidents (id),
+		field       TEXT NOT NULL,
+		old_value   TEXT NOT NULL DEFAULT '',
+		new_value   TEXT NOT NULL DEFAULT '',
+		user_id     TEXT NOT NULL DEFAULT '',
+		changed_at  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX incident_changes_incident_id ON incident_changes (incident_id);`,
+	`ALTER TABLE incidents ADD COLUMN broadcast_channel TEXT NOT NULL DEFAULT '';
+	ALTER TABLE incidents ADD COLUMN broadcast_ts TEXT NOT NULL DEFAULT '';`,
+	`CREATE TABLE sequences (
+		name  TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);`,
+	`ALTER TABLE incidents ADD COLUMN confidential BOOLEAN NOT NULL DEFAULT FALSE;
+	CREATE TABLE audit_log (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		incident_id TEXT NOT NULL REFERENCES incidents (id),
+		action      TEXT NOT NULL,
+		user_id     TEXT NOT NULL DEFAULT '',
+		allowed     BOOLEAN NOT NULL,
+		detail      TEXT NOT NULL DEFAULT '',
+		created_at  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX audit_log_incident_id ON audit_log (incident_id);`,
+	`ALTER TABLE incidents ADD COLUMN roles TEXT NOT NULL DEFAULT '{}';`,
+	`ALTER TABLE action_items ADD COLUMN assignee TEXT NOT NULL DEFAULT '';
+	CREATE INDEX action_items_assignee ON action_items (assignee);`,
+}
+
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore opens (or creates) the SQLite database at path and brings its
+// schema up to date.
+func NewSQLStore(path string) (*SQLStore, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_foreign_keys=on", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open incident database: %w", err)
+	}
+	// SQLite serialises writers anyway; a single connection avoids SQLITE_BUSY
+	// and keeps in-memory databases alive between queries.
+	db.SetMaxOpenConns(1)
+
+	store := &SQLStore{db: db}
+	if err := store.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *SQLStore) migrate(ctx context.Context) error {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+const incidentColumns = `id, description, status, severity, commander_id, comms_rep_id, created_by,
+	created_at, updated_at, resolved_at, channel_id, channel_name, members, timeline_ts, action_items_ts, archived_at, wrap_up_ts, keep_channel,
+	previous_status, reopen_count, broadcast_channel, broadcast_ts, confidential, roles`
+
+func (s *SQLStore) CreateIncident(ctx context.Context, incident *Incident) error {
+	members, err := json.Marshal(nonNilStrings(incident.Members))
+	if err != nil {
+		return fmt.Errorf("failed to encode incident members: %w", err)
+	}
+	roles, err := json.Marshal(nonNilRoles(incident.Roles))
+	if err != nil {
+		return fmt.Errorf("failed to encode incident roles: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if incident.CreatedAt.IsZero() {
+		incident.CreatedAt = now
+	}
+	if incident.UpdatedAt.IsZero() {
+		incident.UpdatedAt = incident.CreatedAt
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO incidents (description, status, severity, commander_id, comms_rep_id,
+		created_by, created_at, updated_at, resolved_at, channel_id, channel_name, members, timeline_ts,
+		action_items_ts, archived_at, wrap_up_ts, keep_channel, previous_status, reopen_count, broadcast_channel,
+		broadcast_ts, confidential, roles)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		incident.Description, incident.Status, incident.Severity, incident.CommanderID, incident.CommsRepID,
+		incident.CreatedBy, incident.CreatedAt, incident.UpdatedAt, nullTime(incident.ResolvedAt),
+		incident.ChannelID, incident.ChannelName, string(members), incident.TimelineTS, incident.ActionItemsTS,
+		nullTime(incident.ArchivedAt), incident.WrapUpTS, incident.KeepChannel, incident.PreviousStatus,
+		incident.ReopenCount, incident.BroadcastChannelID, incident.BroadcastTS, incident.Confidential,
+		string(roles))
+	if err != nil {
+		return fmt.Errorf("failed to insert incident: %w", err)
+	}
+
+	number, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read incident number: %w", err)
+	}
+	id := fmt.Sprintf("INC-%d", number)
+	if _, err := tx.ExecContext(ctx, `UPDATE incidents SET id = ? WHERE number = ?`, id, number); err != nil {
+		return fmt.Errorf("failed to assign incident ID: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit incident: %w", err)
+	}
+
+	incident.ID = id
+	return nil
+}
+
+func (s *SQLStore) GetIncident(ctx context.Context, id string) (*Incident, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+incidentColumns+` FROM incidents WHERE id = ?`, strings.ToUpper(id))
+	return scanIncident(row)
+}
+
+func (s *SQLStore) GetIncidentByChannel(ctx context.Context, channelID string) (*Incident, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+incidentColumns+` FROM incidents WHERE channel_id = ?
+		ORDER BY number DESC LIMIT 1`, channelID)
+	return scanIncident(row)
+}
+
+func (s *SQLStore) UpdateIncident(ctx context.Context, incident *Incident) error {
+	members, err := json.Marshal(nonNilStrings(incident.Members))
+	if err != nil {
+		return fmt.Errorf("failed to encode incident members: %w", err)
+	}
+	roles, err := json.Marshal(nonNilRoles(incident.Roles))
+	if err != nil {
+		return fmt.Errorf("failed to encode incident roles: %w", err)
+	}
+
+	incident.UpdatedAt = time.Now().UTC()
+	res, err := s.db.ExecContext(ctx, `UPDATE incidents SET description = ?, status = ?, severity = ?,
+		commander_id = ?, comms_rep_id = ?, updated_at = ?, resolved_at = ?, channel_id = ?, channel_name = ?,
+		members = ?, timeline_ts = ?, action_items_ts = ?, archived_at = ?, wrap_up_ts = ?, keep_channel = ?,
+		previous_status = ?, reopen_count = ?, broadcast_channel = ?, broadcast_ts = ?,
+		confidential = ?, roles = ? WHERE id = ?`,
+		incident.Description, incident.Status, incident.Severity, incident.CommanderID, incident.CommsRepID,
+		incident.UpdatedAt, nullTime(incident.ResolvedAt), incident.ChannelID, incident.ChannelName,
+		string(members), incident.TimelineTS, incident.ActionItemsTS, nullTime(incident.ArchivedAt),
+		incident.WrapUpTS, incident.KeepChannel, incident.PreviousStatus, incident.ReopenCount,
+		incident.BroadcastChannelID, incident.BroadcastTS, incident.Confidential, string(roles), incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update incident %s: %w", incident.ID, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update incident %s: %w", incident.ID, err)
+	}
+	if n == 0 {
+		return ErrIncidentNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteIncident(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM incidents WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete incident %s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete incident %s: %w", id, err)
+	}
+	if n == 0 {
+		return ErrIncidentNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) NextSequence(ctx context.Context, name string) (int, error) {
+	var value int
+	err := s.db.QueryRowContext(ctx, `INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1 RETURNING value`, name).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance sequence %s: %w", name, err)
+	}
+	return value, nil
+}
+
+func (s *SQLStore) ListIncidents(ctx context.Context, filter IncidentFilter) ([]*Incident, error) {
+	var where []string
+	var args []any
+
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = "?"
+			args = append(args, status)
+		}
+		where = append(where, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if filter.Severity != "" {
+		where = append(where, "severity = ?")
+		args = append(args, filter.Severity)
+	}
+	if filter.Unarchived {
+		where = append(where, "archived_at IS NULL")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UTC())
+	}
+
+	query := `SELECT ` + incidentColumns + ` FROM incidents`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY number DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []*Incident
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+func (s *SQLStore) AddTimelineItem(ctx context.Context, item *TimelineItem) error {
+	if item.Timestamp.IsZero() {
+		item.Timestamp = time.Now().UTC()
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO timeline_items (incident_id, user_id, message, created_at)
+		VALUES (?, ?, ?, ?)`, item.IncidentID, item.User, item.Message, item.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to insert timeline item: %w", err)
+	}
+
+	item.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read timeline item ID: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListTimelineItems(ctx context.Context, incidentID string) ([]TimelineItem, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, incident_id, user_id, message, created_at
+		FROM timeline_items WHERE incident_id = ? ORDER BY created_at, id`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timeline items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []TimelineItem
+	for rows.Next() {
+		var item TimelineItem
+		if err := rows.Scan(&item.ID, &item.IncidentID, &item.User, &item.Message, &item.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to read timeline item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list timeline items: %w", err)
+	}
+	return items, nil
+}
+
+func (s *SQLStore) AddIncidentChange(ctx context.Context, change *IncidentChange) error {
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now().UTC()
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO incident_changes (incident_id, field, old_value, new_value, user_id, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, change.IncidentID, change.Field, change.From, change.To, change.User, change.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert incident change: %w", err)
+	}
+
+	change.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read incident change ID: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListIncidentChanges(ctx context.Context, incidentID string) ([]IncidentChange, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, incident_id, field, old_value, new_value, user_id, changed_at
+		FROM incident_changes WHERE incident_id = ? ORDER BY changed_at, id`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incident changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []IncidentChange
+	for rows.Next() {
+		var change IncidentChange
+		if err := rows.Scan(&change.ID, &change.IncidentID, &change.Field, &change.From, &change.To, &change.User, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to read incident change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list incident changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (s *SQLStore) AddAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO audit_log (incident_id, action, user_id, allowed, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, entry.IncidentID, entry.Action, entry.User, entry.Allowed, entry.Detail, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read audit entry ID: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListAuditEntries(ctx context.Context, incidentID string) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, incident_id, action, user_id, allowed, detail, created_at
+		FROM audit_log WHERE incident_id = ? ORDER BY created_at, id`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.IncidentID, &entry.Action, &entry.User, &entry.Allowed, &entry.Detail, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *SQLStore) AddActionItem(ctx context.Context, item *ActionItem) error {
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var number int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(number), 0) + 1 FROM action_items WHERE incident_id = ?`,
+		item.IncidentID).Scan(&number)
+	if err != nil {
+		return fmt.Errorf("failed to allocate action item number: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO action_items (incident_id, number, description, user_id, assignee,
+		completed, completed_by, completed_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.IncidentID, number, item.Description, item.User, item.Assignee, item.Completed, item.CompletedBy,
+		nullTime(item.CompletedAt), item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert action item: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read action item ID: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit action item: %w", err)
+	}
+
+	item.ID = id
+	item.Number = number
+	return nil
+}
+
+const actionItemColumns = `id, incident_id, number, description, user_id, assignee, completed, completed_by, completed_at, created_at`
+
+func (s *SQLStore) GetActionItem(ctx context.Context, incidentID string, number int) (*ActionItem, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+actionItemColumns+` FROM action_items
+		WHERE incident_id = ? AND number = ?`, incidentID, number)
+	item, err := scanActionItem(row)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *SQLStore) UpdateActionItem(ctx context.Context, item *ActionItem) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE action_items SET description = ?, user_id = ?, assignee = ?,
+		completed = ?, completed_by = ?, completed_at = ? WHERE id = ?`,
+		item.Description, item.User, item.Assignee, item.Completed, item.CompletedBy, nullTime(item.CompletedAt), item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update action item %d: %w", item.ID, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update action item %d: %w", item.ID, err)
+	}
+	if n == 0 {
+		return ErrActionItemNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) ListActionItems(ctx context.Context, incidentID string) ([]ActionItem, error) {
+	return s.queryActionItems(ctx, `SELECT `+actionItemColumns+` FROM action_items
+		WHERE incident_id = ? ORDER BY number`, incidentID)
+}
+
+func (s *SQLStore) ListOpenActionItemsByAssignee(ctx context.Context, userID string) ([]ActionItem, error) {
+	return s.queryActionItems(ctx, `SELECT `+actionItemColumns+` FROM action_items
+		WHERE assignee = ? AND NOT completed ORDER BY created_at, id`, userID)
+}
+
+func (s *SQLStore) queryActionItems(ctx context.Context, query string, args ...any) ([]ActionItem, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list action items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []ActionItem
+	for rows.Next() {
+		item, err := scanActionItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list action items: %w", err)
+	}
+	return items, nil
+}
+
+func (s *SQLStore) GetAlert(ctx context.Context, fingerprint string) (*IncidentAlert, error) {
+	var alert IncidentAlert
+	err := s.db.QueryRowContext(ctx, `SELECT fingerprint, incident_id, group_key, name, status, starts_at, updated_at
+		FROM alerts WHERE fingerprint = ?`, fingerprint).
+		Scan(&alert.Fingerprint, &alert.IncidentID, &alert.GroupKey, &alert.Name, &alert.Status, &alert.StartsAt, &alert.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+	return &alert, nil
+}
+
+func (s *SQLStore) SaveAlert(ctx context.Context, alert *IncidentAlert) error {
+	alert.UpdatedAt = time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO alerts (fingerprint, incident_id, group_key, name, status, starts_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, alert.Fingerprint, alert.IncidentID, alert.GroupKey, alert.Name, alert.Status, alert.StartsAt, alert.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save alert: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListAlerts(ctx context.Context, filter AlertFilter) ([]IncidentAlert, error) {
+	query := `SELECT fingerprint, incident_id, group_key, name, status, starts_at, updated_at FROM alerts`
+	var conditions []string
+	var args []any
+	if filter.IncidentID != "" {
+		conditions = append(conditions, "incident_id = ?")
+		args = append(args, filter.IncidentID)
+	}
+	if filter.GroupKey != "" {
+		conditions = append(conditions, "group_key = ?")
+		args = append(args, filter.GroupKey)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY updated_at DESC, fingerprint"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []IncidentAlert
+	for rows.Next() {
+		var alert IncidentAlert
+		if err := rows.Scan(&alert.Fingerprint, &alert.IncidentID, &alert.GroupKey, &alert.Name, &alert.Status, &alert.StartsAt, &alert.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+func (s *SQLStore) AddDeadLetter(ctx context.Context, letter *DeadLetter) error {
+	if letter.CreatedAt.IsZero() {
+		letter.CreatedAt = time.Now().UTC()
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO webhook_dead_letters (delivery_id, url, event, payload, attempts, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, letter.DeliveryID, letter.URL, letter.Event, letter.Payload, letter.Attempts, letter.LastError, letter.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert dead letter: %w", err)
+	}
+
+	letter.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read dead letter ID: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListDeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, delivery_id, url, event, payload, attempts, last_error, created_at
+		FROM webhook_dead_letters ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var letter DeadLetter
+		err := rows.Scan(&letter.ID, &letter.DeliveryID, &letter.URL, &letter.Event, &letter.Payload, &letter.Attempts, &letter.LastError, &letter.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+func scanActionItem(row rowScanner) (ActionItem, error) {
+	var item ActionItem
+	var completedAt sql.NullTime
+
+	err := row.Scan(&item.ID, &item.IncidentID, &item.Number, &item.Description, &item.User, &item.Assignee, &item.Completed,
+		&item.CompletedBy, &completedAt, &item.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return item, ErrActionItemNotFound
+	}
+	if err != nil {
+		return item, fmt.Errorf("failed to read action item: %w", err)
+	}
+
+	if completedAt.Valid {
+		item.CompletedAt = &completedAt.Time
+	}
+	return item, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanIncident(row rowScanner) (*Incident, error) {
+	var incident Incident
+	var resolvedAt, archivedAt sql.NullTime
+	var members, roles string
+
+	err := row.Scan(&incident.ID, &incident.Description, &incident.Status, &incident.Severity,
+		&incident.CommanderID, &incident.CommsRepID, &incident.CreatedBy, &incident.CreatedAt,
+		&incident.UpdatedAt, &resolvedAt, &incident.ChannelID, &incident.ChannelName, &members,
+		&incident.TimelineTS, &incident.ActionItemsTS, &archivedAt, &incident.WrapUpTS, &incident.KeepChannel,
+		&incident.PreviousStatus, &incident.ReopenCount, &incident.BroadcastChannelID, &incident.BroadcastTS,
+		&incident.Confidential, &roles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIncidentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read incident: %w", err)
+	}
+
+	if resolvedAt.Valid {
+		incident.ResolvedAt = &resolvedAt.Time
+	}
+	if archivedAt.Valid {
+		incident.ArchivedAt = &archivedAt.Time
+	}
+	if err := json.Unmarshal([]byte(members), &incident.Members); err != nil {
+		return nil, fmt.Errorf("failed to decode members of incident %s: %w", incident.ID, err)
+	}
+	if err := json.Unmarshal([]byte(roles), &incident.Roles); err != nil {
+		return nil, fmt.Errorf("failed to decode roles of incident %s: %w", incident.ID, err)
+	}
+	if len(incident.Roles) == 0 {
+		incident.Roles = nil
+	}
+	return &incident, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nonNilRoles(roles map[string]string) map[string]string {
+	if roles == nil {
+		return map[string]string{}
+	}
+	return roles
+}
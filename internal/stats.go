@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const defaultStatsPeriod = 30 * 24 * time.Hour
+
+// IncidentStats summarises the incidents declared in a period. Durations are
+// means over the incidents they apply to; in JSON they are in seconds.
+type IncidentStats struct {
+	// Since is zero for all incidents.
+	Since      time.Time
+	Until      time.Time
+	Total      int
+	BySeverity map[Severity]int
+	// Resolved counts incidents that are resolved now, which are the ones
+	// the time to resolve is measured over.
+	Resolved int
+	// MeanTimeToAcknowledge is how long incidents went without a commander.
+	// Incidents declared with one count as zero; ones that never had one are
+	// left out.
+	MeanTimeToAcknowledge time.Duration
+	Acknowledged          int
+	MeanTimeToResolve     time.Duration
+	P90TimeToResolve      time.Duration
+	// TimeInStatus is the mean time spent in each open status by the
+	// incidents that were in it. Incidents declared before status changes
+	// were recorded are left out.
+	TimeInStatus map[Status]time.Duration
+	// Reopened counts incidents reopened at least once; ReopenRate is that
+	// over the incidents resolved at least once.
+	Reopened   int
+	ReopenRate float64
+}
+
+func (st *IncidentStats) MarshalJSON() ([]byte, error) {
+	seconds := func(d time.Duration) float64 { return math.Round(d.Seconds()) }
+	timeInStatus := make(map[Status]float64, len(st.TimeInStatus))
+	for status, d := range st.TimeInStatus {
+		timeInStatus[status] = seconds(d)
+	}
+	var since *time.Time
+	if !st.Since.IsZero() {
+		since = &st.Since
+	}
+	return json.Marshal(struct {
+		Since                        *time.Time         `json:"since"`
+		Until                        time.Time          `json:"until"`
+		Total                        int                `json:"total"`
+		BySeverity                   map[Severity]int   `json:"by_severity"`
+		Resolved                     int                `json:"resolved"`
+		Acknowledged                 int                `json:"acknowledged"`
+		MeanTimeToAcknowledgeSeconds float64            `json:"mean_time_to_acknowledge_seconds"`
+		MeanTimeToResolveSeconds     float64            `json:"mean_time_to_resolve_seconds"`
+		P90TimeToResolveSeconds      float64            `json:"p90_time_to_resolve_seconds"`
+		TimeInStatusSeconds          map[Status]float64 `json:"mean_time_in_status_seconds"`
+		Reopened                     int                `json:"reopened"`
+		ReopenRate                   float64            `json:"reopen_rate"`
+	}{since, st.Until, st.Total, st.BySeverity, st.Resolved, st.Acknowledged,
+		seconds(st.MeanTimeToAcknowledge), seconds(st.MeanTimeToResolve), seconds(st.P90TimeToResolve),
+		timeInStatus, st.Reopened, st.ReopenRate})
+}
+
+// parseStatsPeriod reads the `/incident stats` period: a number of hours,
+// days or weeks like "24h", "30d" or "12w", or "all". It defaults to 30 days
+// and returns zero for "all".
+func parseStatsPeriod(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return defaultStatsPeriod, nil
+	case "all":
+		return 0, nil
+	}
+
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	unit, ok := units[s[len(s)-1]]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if !ok || err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid period %q, use e.g. 7d, 12w or all", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Stats computes statistics for the incidents declared in the given period
+// before now, or all incidents for a zero period.
+func (s *IncidentService) Stats(ctx context.Context, period time.Duration, now time.Time) (*IncidentStats, error) {
+	stats := &IncidentStats{
+		Until:        now.UTC(),
+		BySeverity:   make(map[Severity]int),
+		TimeInStatus: make(map[Status]time.Duration),
+	}
+	if period > 0 {
+		stats.Since = now.Add(-period).UTC()
+	}
+
+	incidents, err := s.store.ListIncidents(ctx, IncidentFilter{CreatedAfter: stats.Since})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+
+	var acknowledge, resolve []time.Duration
+	statusTotals := make(map[Status]time.Duration)
+	statusCounts := make(map[Status]int)
+	everResolved := 0
+	for _, incident := range incidents {
+		stats.Total++
+		stats.BySeverity[incident.Severity]++
+		if incident.Status == StatusResolved {
+			stats.Resolved++
+		}
+		if incident.ResolvedAt != nil {
+			resolve = append(resolve, incident.ResolvedAt.Sub(incident.CreatedAt))
+		}
+		if incident.ResolvedAt != nil || incident.ReopenCount > 0 {
+			everResolved++
+		}
+		if incident.ReopenCount > 0 {
+			stats.Reopened++
+		}
+
+		changes, err := s.store.ListIncidentChanges(ctx, incident.ID)
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := timeToAcknowledge(incident, changes); ok {
+			acknowledge = append(acknowledge, d)
+		}
+		for status, d := range timeInStatus(changes, now) {
+			statusTotals[status] += d
+			statusCounts[status]++
+		}
+	}
+
+	stats.Acknowledged = len(acknowledge)
+	stats.MeanTimeToAcknowledge = meanDuration(acknowledge)
+	stats.MeanTimeToResolve = meanDuration(resolve)
+	stats.P90TimeToResolve = percentileDuration(resolve, 0.9)
+	for status, total := range statusTotals {
+		stats.TimeInStatus[status] = total / time.Duration(statusCounts[status])
+	}
+	if everResolved > 0 {
+		stats.ReopenRate = float64(stats.Reopened) / float64(everResolved)
+	}
+	return stats, nil
+}
+
+// timeToAcknowledge is how long after being declared the incident first had
+// a commander.
+func timeToAcknowledge(incident *Incident, changes []IncidentChange) (time.Duration, bool) {
+	for _, change := range changes {
+		if change.Field == ChangeCommander && change.To != "" {
+			return max(change.ChangedAt.Sub(incident.CreatedAt), 0), true
+		}
+	}
+	return 0, false
+}
+
+// timeInStatus replays the status changes of an incident and sums the time
+// spent in each open status, up to now for the current one.
+func timeInStatus(changes []IncidentChange, now time.Time) map[Status]time.Duration {
+	durations := make(map[Status]time.Duration)
+	var current Status
+	var since time.Time
+	for _, change := range changes {
+		if change.Field != ChangeStatus {
+			continue
+		}
+		if current != "" && current != StatusResolved {
+			durations[current] += change.ChangedAt.Sub(since)
+		}
+		current, since = Status(change.To), change.ChangedAt
+	}
+	if current != "" && current != StatusResolved {
+		durations[current] += now.Sub(since)
+	}
+	return durations
+}
+
+func meanDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// percentileDuration returns the nearest-rank percentile p (0-1).
+func percentileDuration(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// StatsMessage renders the `/incident stats` response.
+func (s *IncidentService) StatsMessage(stats *IncidentStats) []slack.Block {
+	taxonomy := s.config().Taxonomy
+	period := "all time"
+	if !stats.Since.IsZero() {
+		period = fmt.Sprintf("%s – %s", stats.Since.Format("Jan 2, 2006"), stats.Until.Format("Jan 2, 2006"))
+	}
+	header := fmt.Sprintf("*Incident stats, %s*", period)
+	if stats.Total == 0 {
+		return []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", header+"\nNo incidents were declared.", false, false), nil, nil)}
+	}
+
+	severities := make([]string, 0, len(taxonomy.Severities))
+	for _, level := range taxonomy.Severities {
+		severities = append(severities, fmt.Sprintf("%s: %d", level.Label, stats.BySeverity[level.ID]))
+	}
+	lines := []string{
+		header,
+		fmt.Sprintf("*Incidents:* %d (%s), %d resolved", stats.Total, strings.Join(severities, ", "), stats.Resolved),
+		fmt.Sprintf("*Time to resolve:* mean %s, p90 %s", statsDuration(stats.MeanTimeToResolve, stats.Resolved), statsDuration(stats.P90TimeToResolve, stats.Resolved)),
+		fmt.Sprintf("*Time to acknowledge:* mean %s (time until a commander was assigned)", statsDuration(stats.MeanTimeToAcknowledge, stats.Acknowledged)),
+	}
+
+	var inStatus []string
+	for _, level := range taxonomy.Statuses {
+		if d, ok := stats.TimeInStatus[level.ID]; ok {
+			inStatus = append(inStatus, fmt.Sprintf("%s %s", level.Label, formatAge(d)))
+		}
+	}
+	if len(inStatus) > 0 {
+		lines = append(lines, "*Mean time in status:* "+strings.Join(inStatus, ", "))
+	}
+	lines = append(lines, fmt.Sprintf("*Reopened:* %d (%.0f%% of resolved incidents)", stats.Reopened, stats.ReopenRate*100))
+
+	return []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", strings.Join(lines, "\n"), false, false), nil, nil)}
+}
+
+// statsDuration renders a mean, or "n/a" if nothing was measured.
+func statsDuration(d time.Duration, n int) string {
+	if n == 0 {
+		return "n/a"
+	}
+	return formatAge(d)
+}
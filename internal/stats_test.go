@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	seed := func(incident *Incident, changes ...IncidentChange) {
+		t.Helper()
+		if err := app.store.CreateIncident(ctx, incident); err != nil {
+			t.Fatalf("CreateIncident: %v", err)
+		}
+		for _, change := range changes {
+			change.IncidentID = incident.ID
+			if err := app.store.AddIncidentChange(ctx, &change); err != nil {
+				t.Fatalf("AddIncidentChange: %v", err)
+			}
+		}
+	}
+	status := func(from, to Status, at time.Time) IncidentChange {
+		return IncidentChange{Field: ChangeStatus, From: string(from), To: string(to), ChangedAt: at}
+	}
+	commander := func(to string, at time.Time) IncidentChange {
+		return IncidentChange{Field: ChangeCommander, To: to, ChangedAt: at}
+	}
+
+	// Fixed after an hour of investigating, resolved after four.
+	a := now.Add(-10 * 24 * time.Hour)
+	resolvedA := a.Add(4 * time.Hour)
+	seed(&Incident{Description: "A", Severity: SeveritySev1, Status: StatusResolved, CreatedAt: a, ResolvedAt: &resolvedA, CommanderID: "U1"},
+		status("", StatusInvestigating, a),
+		commander("U1", a.Add(30*time.Minute)),
+		status(StatusInvestigating, StatusFixing, a.Add(time.Hour)),
+		status(StatusFixing, StatusResolved, resolvedA))
+	// Resolved, reopened and resolved again.
+	b := now.Add(-5 * 24 * time.Hour)
+	resolvedB := b.Add(2 * time.Hour)
+	seed(&Incident{Description: "B", Severity: SeveritySev2, Status: StatusResolved, CreatedAt: b, ResolvedAt: &resolvedB, CommanderID: "U2", ReopenCount: 1},
+		status("", StatusInvestigating, b),
+		commander("U2", b),
+		status(StatusInvestigating, StatusResolved, b.Add(time.Hour)),
+		status(StatusResolved, StatusInvestigating, b.Add(90*time.Minute)),
+		status(StatusInvestigating, StatusResolved, resolvedB))
+	// Still open, without a commander.
+	c := now.Add(-time.Hour)
+	seed(&Incident{Description: "C", Severity: SeveritySev2, Status: StatusInvestigating, CreatedAt: c},
+		status("", StatusInvestigating, c))
+	// Outside the default period.
+	seed(&Incident{Description: "D", Severity: SeveritySev3, Status: StatusInvestigating, CreatedAt: now.Add(-40 * 24 * time.Hour)})
+
+	stats, err := app.service.Stats(ctx, defaultStatsPeriod, now)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Total != 3 || stats.BySeverity[SeveritySev1] != 1 || stats.BySeverity[SeveritySev2] != 2 || stats.Resolved != 2 {
+		t.Errorf("counts = %+v", stats)
+	}
+	if stats.MeanTimeToResolve != 3*time.Hour || stats.P90TimeToResolve != 4*time.Hour {
+		t.Errorf("time to resolve: mean %s, p90 %s", stats.MeanTimeToResolve, stats.P90TimeToResolve)
+	}
+	if stats.MeanTimeToAcknowledge != 15*time.Minute || stats.Acknowledged != 2 {
+		t.Errorf("time to acknowledge: mean %s over %d", stats.MeanTimeToAcknowledge, stats.Acknowledged)
+	}
+	if stats.TimeInStatus[StatusInvestigating] != 70*time.Minute || stats.TimeInStatus[StatusFixing] != 3*time.Hour {
+		t.Errorf("time in status = %v", stats.TimeInStatus)
+	}
+	if stats.Reopened != 1 || stats.ReopenRate != 0.5 {
+		t.Errorf("reopened %d, rate %v", stats.Reopened, stats.ReopenRate)
+	}
+
+	if all, _ := app.service.Stats(ctx, 0, now); all.Total != 4 {
+		t.Errorf("all-time total = %d", all.Total)
+	}
+
+	var body map[string]any
+	if code := app.api(t, http.MethodGet, "/stats", "", &body); code != http.StatusOK || body["total"] != 3.0 || body["p90_time_to_resolve_seconds"] != 14400.0 {
+		t.Errorf("API stats: status %d, body %v", code, body)
+	}
+	if code := app.api(t, http.MethodGet, "/stats?period=soon", "", nil); code != http.StatusBadRequest {
+		t.Errorf("API stats with a bad period: status %d", code)
+	}
+
+	app.command(t, "C0GENERAL", "stats 7d")
+	eventually(t, "stats response", func() bool {
+		for _, call := range app.slack.Calls("response_url") {
+			blocks, _ := json.Marshal(call.JSON["blocks"])
+			if strings.Contains(string(blocks), "*Incidents:* 2 (SEV-0: 0, SEV-1: 0, SEV-2: 2, SEV-3: 0), 1 resolved") {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrIncidentNotFound     = errors.New("incident not found")
+	ErrActionItemNotFound   = errors.New("action item not found")
+	ErrIncidentNotResolved  = errors.New("incident is not resolved")
+	ErrAlertNotFound        = errors.New("alert not found")
+	ErrIncidentConfidential = errors.New("incident is confidential")
+)
+
+// IncidentStore persists incident state independently of the Slack channel
+// topic and pinned messages, which users can edit by hand.
+type IncidentStore interface {
+	// CreateIncident stores a new incident and assigns its ID.
+	CreateIncident(ctx context.Context, incident *Incident) error
+	GetIncident(ctx context.Context, id string) (*Incident, error)
+	GetIncidentByChannel(ctx context.Context, channelID string) (*Incident, error)
+	UpdateIncident(ctx context.Context, incident *Incident) error
+	// DeleteIncident removes an incident that has nothing attached to it
+	// yet, like one whose channel couldn't be created.
+	DeleteIncident(ctx context.Context, id string) error
+	ListIncidents(ctx context.Context, filter IncidentFilter) ([]*Incident, error)
+
+	AddTimelineItem(ctx context.Context, item *TimelineItem) error
+	// ListTimelineItems returns an incident's timeline, oldest first.
+	ListTimelineItems(ctx context.Context, incidentID string) ([]TimelineItem, error)
+
+	// AddActionItem stores a new action item and assigns its ID and Number.
+	AddActionItem(ctx context.Context, item *ActionItem) error
+	GetActionItem(ctx context.Context, incidentID string, number int) (*ActionItem, error)
+	UpdateActionItem(ctx context.Context, item *ActionItem) error
+	// ListActionItems returns an incident's action items ordered by Number.
+	ListActionItems(ctx context.Context, incidentID string) ([]ActionItem, error)
+	// ListOpenActionItemsByAssignee returns the incomplete action items
+	// assigned to a user across all incidents, oldest first.
+	ListOpenActionItemsByAssignee(ctx context.Context, userID string) ([]ActionItem, error)
+
+	AddIncidentChange(ctx context.Context, change *IncidentChange) error
+	// ListIncidentChanges returns an incident's changes, oldest first.
+	ListIncidentChanges(ctx context.Context, incidentID string) ([]IncidentChange, error)
+
+	AddAuditEntry(ctx context.Context, entry *AuditEntry) error
+	// ListAuditEntries returns an incident's audit log, oldest first.
+	ListAuditEntries(ctx context.Context, incidentID string) ([]AuditEntry, error)
+
+	GetAlert(ctx context.Context, fingerprint string) (*IncidentAlert, error)
+	// SaveAlert creates or replaces the alert with the same fingerprint.
+	SaveAlert(ctx context.Context, alert *IncidentAlert) error
+	ListAlerts(ctx context.Context, filter AlertFilter) ([]IncidentAlert, error)
+
+	// AddDeadLetter records a webhook delivery that failed every attempt.
+	AddDeadLetter(ctx context.Context, letter *DeadLetter) error
+	// ListDeadLetters returns the most recent dead letters first.
+	ListDeadLetters(ctx context.Context, limit int) ([]DeadLetter, error)
+
+	// NextSequence advances the named counter and returns its new value,
+	// starting from 1.
+	NextSequence(ctx context.Context, name string) (int, error)
+
+	Close() error
+}
+
+// IncidentFilter narrows ListIncidents. Zero values match everything.
+type IncidentFilter struct {
+	Statuses []Status
+	Severity Severity
+	// Unarchived excludes incidents whose channel has been archived.
+	Unarchived bool
+	// CreatedAfter, if set, excludes incidents declared before it.
+	CreatedAfter time.Time
+	Limit        int
+	// Offset skips that many incidents first. It only applies with Limit.
+	Offset int
+}
+
+// AlertFilter narrows ListAlerts. Zero values match everything.
+type AlertFilter struct {
+	IncidentID string
+	GroupKey   string
+}
@@ -0,0 +1,349 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Taxonomy defines the severities, statuses and roles an incident can have.
+// The modals, help text, list filters and per-severity policies are all
+// driven by it, so teams can rename or add levels without code changes.
+type Taxonomy struct {
+	// Severities are ordered from most to least severe.
+	Severities []SeverityLevel `yaml:"severities"`
+	// Statuses are in workflow order. They must include StatusResolved, which
+	// HAL uses for resolving and reopening incidents; the first other status
+	// is the default for reopened incidents.
+	Statuses []StatusLevel `yaml:"statuses"`
+	// Roles are the people incidents can be assigned, in display order. They
+	// must include RoleCommander and RoleComms.
+	Roles []RoleDefinition `yaml:"roles"`
+	// OnCall is who to mention when an incident reaches a severity with
+	// PageOnCall, e.g. "<!subteam^S0123ABCD>" for a user group.
+	OnCall string `yaml:"oncall"`
+}
+
+type SeverityLevel struct {
+	ID          Severity `yaml:"id"`
+	Label       string   `yaml:"label"`
+	Description string   `yaml:"description"`
+	// Color is a hex colour like "#d0021b" for messages about the severity.
+	Color string `yaml:"color"`
+	// RequiresPostmortem adds a postmortem action item when an incident is
+	// declared at or updated to this severity.
+	RequiresPostmortem bool `yaml:"requires_postmortem"`
+	// PageOnCall mentions the taxonomy's OnCall in the incident channel when
+	// an incident is declared at or escalated to this severity.
+	PageOnCall bool `yaml:"page_oncall"`
+	// ArchiveAfter is how long after the resolution wrap-up the incident
+	// channel is archived. Zero keeps the channel.
+	ArchiveAfter time.Duration `yaml:"archive_after"`
+}
+
+type StatusLevel struct {
+	ID          Status `yaml:"id"`
+	Label       string `yaml:"label"`
+	Description string `yaml:"description"`
+}
+
+type RoleDefinition struct {
+	ID          string `yaml:"id"`
+	Label       string `yaml:"label"`
+	Description string `yaml:"description"`
+	// Checklist is sent to whoever is assigned the role.
+	Checklist []string `yaml:"checklist"`
+}
+
+// defaultRoles are used when the taxonomy doesn't define any.
+func defaultRoles() []RoleDefinition {
+	return []RoleDefinition{
+		{ID: RoleCommander, Label: "Incident Commander", Description: "Coordinates the response and makes the calls.", Checklist: []string{
+			"Confirm the severity and status with `/incident update`",
+			"Make sure every workstream has an owner",
+			"Post an update on the timeline at least every 30 minutes",
+			"Hand off with `/incident handoff @user` before you step away",
+		}},
+		{ID: RoleComms, Label: "Comms Representative", Description: "Keeps stakeholders and customers informed.", Checklist: []string{
+			"Agree an update cadence with the commander",
+			"Post status page and customer updates",
+			"Answer questions from outside the incident channel",
+		}},
+		{ID: RoleScribe, Label: "Scribe", Description: "Keeps the timeline.", Checklist: []string{
+			"Record decisions and findings with `/incident timeline`",
+			"Capture follow-ups with `/incident ai`",
+		}},
+		{ID: RoleTechLead, Label: "Tech Lead", Description: "Leads the technical investigation and fix.", Checklist: []string{
+			"Form and share a working theory of the cause",
+			"Coordinate the engineers working on the fix",
+			"Tell the commander before making risky changes",
+		}},
+	}
+}
+
+// DefaultTaxonomy is used when the configuration doesn't define one.
+func DefaultTaxonomy() *Taxonomy {
+	return &Taxonomy{
+		Severities: []SeverityLevel{
+			{ID: SeveritySev0, Label: "SEV-0", Description: "Critical: High impact, affects many users.", Color: "#d0021b", ArchiveAfter: 30 * 24 * time.Hour},
+			{ID: SeveritySev1, Label: "SEV-1", Description: "Major: Significant impact, affects some users.", Color: "#f5a623", RequiresPostmortem: true, ArchiveAfter: 14 * 24 * time.Hour},
+			{ID: SeveritySev2, Label: "SEV-2", Description: "Moderate: Non-critical impact or user inconvenience.", Color: "#f8e71c", RequiresPostmortem: true, ArchiveAfter: 7 * 24 * time.Hour},
+			{ID: SeveritySev3, Label: "SEV-3", Description: "Minor: Low priority, no immediate attention needed.", Color: "#4a90e2", ArchiveAfter: 2 * 24 * time.Hour},
+		},
+		Statuses: []StatusLevel{
+			{ID: StatusInvestigating, Label: "Investigating", Description: "Incident is under investigation."},
+			{ID: StatusFixing, Label: "Fixing", Description: "A fix is being implemented."},
+			{ID: StatusMonitoring, Label: "Monitoring", Description: "Fix implemented, monitoring for stability."},
+			{ID: StatusResolved, Label: "Resolved", Description: "The incident has been resolved."},
+		},
+		Roles: defaultRoles(),
+	}
+}
+
+// LoadTaxonomy reads a taxonomy from a YAML file. Levels without a label use
+// their ID.
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open taxonomy: %w", err)
+	}
+	defer f.Close()
+
+	var taxonomy Taxonomy
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&taxonomy); err != nil {
+		return nil, fmt.Errorf("failed to parse taxonomy %s: %w", path, err)
+	}
+	taxonomy.applyDefaults()
+
+	if err := taxonomy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid taxonomy %s: %w", path, err)
+	}
+	return &taxonomy, nil
+}
+
+// applyDefaults labels levels and roles without a label with their ID, and
+// adds the default roles if none are defined.
+func (t *Taxonomy) applyDefaults() {
+	if len(t.Roles) == 0 {
+		t.Roles = defaultRoles()
+	}
+	for i := range t.Roles {
+		if t.Roles[i].Label == "" {
+			t.Roles[i].Label = t.Roles[i].ID
+		}
+	}
+	for i := range t.Severities {
+		if t.Severities[i].Label == "" {
+			t.Severities[i].Label = string(t.Severities[i].ID)
+		}
+	}
+	for i := range t.Statuses {
+		if t.Statuses[i].Label == "" {
+			t.Statuses[i].Label = string(t.Statuses[i].ID)
+		}
+	}
+}
+
+// Validate checks that IDs are present and unique, that there is a resolved
+// status and at least one open one, and that the built-in roles are defined.
+func (t *Taxonomy) Validate() error {
+	var errs []error
+	if len(t.Severities) == 0 {
+		errs = append(errs, errors.New("no severities defined"))
+	}
+	seen := make(map[string]bool)
+	for i, level := range t.Severities {
+		switch {
+		case level.ID == "":
+			errs = append(errs, fmt.Errorf("severity %d has no id", i+1))
+		case strings.Contains(string(level.ID), " incident: ") || strings.Contains(string(level.ID), "|"):
+			errs = append(errs, fmt.Errorf("severity %q must not contain \"|\" or \" incident: \"", level.ID))
+		case seen[normalizeLevel(string(level.ID))]:
+			errs = append(errs, fmt.Errorf("severity %q is defined twice", level.ID))
+		}
+		seen[normalizeLevel(string(level.ID))] = true
+		if level.Color != "" && !isHexColor(level.Color) {
+			errs = append(errs, fmt.Errorf("severity %q has invalid color %q", level.ID, level.Color))
+		}
+		if level.ArchiveAfter < 0 {
+			errs = append(errs, fmt.Errorf("severity %q has a negative archive_after", level.ID))
+		}
+		if level.PageOnCall && t.OnCall == "" {
+			errs = append(errs, fmt.Errorf("severity %q pages on-call but no oncall is set", level.ID))
+		}
+	}
+
+	statuses := make(map[Status]bool)
+	for i, level := range t.Statuses {
+		switch {
+		case level.ID == "":
+			errs = append(errs, fmt.Errorf("status %d has no id", i+1))
+		case statuses[level.ID]:
+			errs = append(errs, fmt.Errorf("status %q is defined twice", level.ID))
+		}
+		statuses[level.ID] = true
+	}
+	if !statuses[StatusResolved] {
+		errs = append(errs, fmt.Errorf("statuses must include %q", StatusResolved))
+	}
+	if len(t.OpenStatuses()) == 0 {
+		errs = append(errs, errors.New("statuses must include at least one open status"))
+	}
+
+	roles := make(map[string]bool)
+	for i, role := range t.Roles {
+		switch {
+		case !roleIDPattern.MatchString(role.ID):
+			errs = append(errs, fmt.Errorf("role %d: id %q must be lower-case letters, digits, - or _", i+1, role.ID))
+		case roles[role.ID]:
+			errs = append(errs, fmt.Errorf("role %q is defined twice", role.ID))
+		}
+		roles[role.ID] = true
+	}
+	for _, id := range []string{RoleCommander, RoleComms} {
+		if !roles[id] {
+			errs = append(errs, fmt.Errorf("roles must include %q", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Severity looks up a severity level by ID.
+func (t *Taxonomy) Severity(id Severity) (SeverityLevel, bool) {
+	for _, level := range t.Severities {
+		if level.ID == id {
+			return level, true
+		}
+	}
+	return SeverityLevel{}, false
+}
+
+// Status looks up a status level by ID.
+func (t *Taxonomy) Status(id Status) (StatusLevel, bool) {
+	for _, level := range t.Statuses {
+		if level.ID == id {
+			return level, true
+		}
+	}
+	return StatusLevel{}, false
+}
+
+// SeverityLabel returns the label of a severity, or its ID if the taxonomy
+// doesn't have it. A nil taxonomy always gives the ID.
+func (t *Taxonomy) SeverityLabel(id Severity) string {
+	if t != nil {
+		if level, ok := t.Severity(id); ok {
+			return level.Label
+		}
+	}
+	return string(id)
+}
+
+// StatusLabel is SeverityLabel for statuses.
+func (t *Taxonomy) StatusLabel(id Status) string {
+	if t != nil {
+		if level, ok := t.Status(id); ok {
+			return level.Label
+		}
+	}
+	return string(id)
+}
+
+// Role looks up a role by ID.
+func (t *Taxonomy) Role(id string) (RoleDefinition, bool) {
+	for _, role := range t.Roles {
+		if role.ID == id {
+			return role, true
+		}
+	}
+	return RoleDefinition{}, false
+}
+
+// ParseRole matches user input against role IDs and labels, ignoring case,
+// spaces and dashes, so "tech-lead", "TechLead" and "Tech Lead" all find
+// tech_lead. "ic" and "comms rep" are accepted for the built-in roles.
+func (t *Taxonomy) ParseRole(s string) (RoleDefinition, bool) {
+	input := normalizeLevel(s)
+	switch input {
+	case "ic":
+		input = RoleCommander
+	case "commsrep":
+		input = RoleComms
+	}
+	for _, role := range t.Roles {
+		if input == normalizeLevel(role.ID) || input == normalizeLevel(role.Label) {
+			return role, true
+		}
+	}
+	return RoleDefinition{}, false
+}
+
+// OpenStatuses returns every status except StatusResolved, in order.
+func (t *Taxonomy) OpenStatuses() []Status {
+	var open []Status
+	for _, level := range t.Statuses {
+		if level.ID != StatusResolved {
+			open = append(open, level.ID)
+		}
+	}
+	return open
+}
+
+// InitialStatus is the first open status.
+func (t *Taxonomy) InitialStatus() Status {
+	if open := t.OpenStatuses(); len(open) > 0 {
+		return open[0]
+	}
+	return StatusInvestigating
+}
+
+// SeverityRank is the position of a severity, 0 being the most severe.
+// Unknown severities rank last.
+func (t *Taxonomy) SeverityRank(id Severity) int {
+	for i, level := range t.Severities {
+		if level.ID == id {
+			return i
+		}
+	}
+	return len(t.Severities)
+}
+
+// ParseSeverity matches user input against severity IDs and labels,
+// ignoring case, spaces and dashes, so "SEV-1", "sev1" and "1" all find
+// SEV-1.
+func (t *Taxonomy) ParseSeverity(s string) (Severity, bool) {
+	input := normalizeLevel(s)
+	for _, level := range t.Severities {
+		id := normalizeLevel(string(level.ID))
+		if input == id || "sev"+input == id || input == normalizeLevel(level.Label) {
+			return level.ID, true
+		}
+	}
+	return "", false
+}
+
+func normalizeLevel(s string) string {
+	return strings.NewReplacer("-", "", " ", "", "_", "").Replace(strings.ToLower(s))
+}
+
+var roleIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func isHexColor(s string) bool {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(hex) != 3 && len(hex) != 6) {
+		return false
+	}
+	for _, c := range strings.ToLower(hex) {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const testTaxonomy = `
+oncall: "<!subteam^S0123>"
+severities:
+  - id: P1
+    label: P1 Outage
+    description: Customers can't use the product.
+    color: "#d0021b"
+    requires_postmortem: true
+    page_oncall: true
+    archive_after: 72h
+  - id: P2
+    description: Degraded service.
+statuses:
+  - id: Triage
+  - id: Mitigated
+    label: Mitigated
+  - id: Resolved
+`
+
+func writeTaxonomy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "taxonomy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadTaxonomy(t *testing.T) {
+	taxonomy, err := LoadTaxonomy(writeTaxonomy(t, testTaxonomy))
+	if err != nil {
+		t.Fatalf("LoadTaxonomy: %v", err)
+	}
+	p1, ok := taxonomy.Severity("P1")
+	if !ok || !p1.PageOnCall || p1.ArchiveAfter != 72*time.Hour {
+		t.Errorf("P1 = %+v", p1)
+	}
+	if p2, _ := taxonomy.Severity("P2"); p2.Label != "P2" {
+		t.Errorf("P2 label = %q, want the ID", p2.Label)
+	}
+	if severity, ok := taxonomy.ParseSeverity("p1 outage"); !ok || severity != "P1" {
+		t.Errorf("ParseSeverity(p1 outage) = %q, %v", severity, ok)
+	}
+	if got := taxonomy.InitialStatus(); got != "Triage" {
+		t.Errorf("InitialStatus() = %q, want Triage", got)
+	}
+	if role, ok := taxonomy.ParseRole("Tech-Lead"); !ok || role.ID != RoleTechLead {
+		t.Errorf("ParseRole(Tech-Lead) = %+v, %v; want the default roles", role, ok)
+	}
+
+	for name, content := range map[string]string{
+		"no resolved status":  "severities: [{id: P1}]\nstatuses: [{id: Open}]",
+		"duplicate severity":  "severities: [{id: P1}, {id: p-1}]\nstatuses: [{id: Open}, {id: Resolved}]",
+		"page without oncall": "severities: [{id: P1, page_oncall: true}]\nstatuses: [{id: Open}, {id: Resolved}]",
+		"bad color":           "severities: [{id: P1, color: red}]\nstatuses: [{id: Open}, {id: Resolved}]",
+		"unknown field":       "severities: [{id: P1, colour: \"#fff\"}]\nstatuses: [{id: Open}, {id: Resolved}]",
+		"no commander role":   "severities: [{id: P1}]\nstatuses: [{id: Open}, {id: Resolved}]\nroles: [{id: comms}]",
+	} {
+		if _, err := LoadTaxonomy(writeTaxonomy(t, content)); err == nil {
+			t.Errorf("%s: LoadTaxonomy succeeded", name)
+		}
+	}
+}
+
+func TestCustomTaxonomy(t *testing.T) {
+	app := newTestApp(t)
+	taxonomy, err := LoadTaxonomy(writeTaxonomy(t, testTaxonomy))
+	if err != nil {
+		t.Fatalf("LoadTaxonomy: %v", err)
+	}
+	app.config.Taxonomy = taxonomy
+
+	app.command(t, "CGENERAL", "create")
+	raw, _ := json.Marshal(app.slack.Calls("views.open")[0].JSON["view"])
+	view := string(raw)
+	for _, want := range []string{`"value":"P1"`, "P1 Outage", "Customers can't use the product.", `"value":"Triage"`} {
+		if !strings.Contains(view, want) {
+			t.Errorf("create modal is missing %s: %s", want, view)
+		}
+	}
+
+	submission := createIncidentSubmission("V1000", "Checkout is down", "P1")
+	submission.View.State.Values["status"] = map[string]slack.BlockAction{
+		"status": {SelectedOption: slack.OptionBlockObject{Value: "Triage"}},
+	}
+	app.interaction(t, submission)
+	eventually(t, "on-call page", func() bool {
+		for _, call := range app.slack.Calls("chat.postMessage") {
+			if strings.Contains(call.Form.Get("attachments"), "subteam^S0123") {
+				return true
+			}
+		}
+		return false
+	})
+
+	eventually(t, "page on timeline", func() bool {
+		items, _ := app.store.ListTimelineItems(context.Background(), app.incident().ID)
+		return len(items) == 2 && strings.Contains(items[1].Message, "Paged on-call")
+	})
+	actionItems, _ := app.store.ListActionItems(context.Background(), app.incident().ID)
+	if len(actionItems) != 1 || actionItems[0].Description != "Create incident postmortem" {
+		t.Errorf("action items = %+v, want the postmortem", actionItems)
+	}
+
+	// Lists and exports show labels rather than IDs.
+	if line := incidentListLine(taxonomy, app.incident(), time.Now()); !strings.Contains(line, "*P1 Outage*") {
+		t.Errorf("list line = %q", line)
+	}
+	export := &IncidentExport{Incident: app.incident()}
+	if md := IncidentMarkdown(export, taxonomy); !strings.Contains(md, "**Severity:** P1 Outage") {
+		t.Errorf("export = %q", md)
+	}
+	if doc, err := app.service.Postmortem(context.Background(), app.incident().ID); err != nil || !strings.Contains(doc, ": P1 Outage by @U1") {
+		t.Errorf("postmortem = %q, %v", doc, err)
+	}
+}
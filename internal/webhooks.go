@@ -0,0 +1,343 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WebhookEvent names a change that is published to outbound webhooks.
+type WebhookEvent string
+
+const (
+	WebhookIncidentCreated  WebhookEvent = "incident.created"
+	WebhookIncidentUpdated  WebhookEvent = "incident.updated"
+	WebhookIncidentResolved WebhookEvent = "incident.resolved"
+	WebhookTimelineAdded    WebhookEvent = "timeline.added"
+	WebhookActionItemAdded  WebhookEvent = "action_item.added"
+)
+
+var webhookEvents = []WebhookEvent{WebhookIncidentCreated, WebhookIncidentUpdated, WebhookIncidentResolved, WebhookTimelineAdded, WebhookActionItemAdded}
+
+const (
+	webhookWorkers      = 4
+	webhookQueueSize    = 256
+	webhookMaxRetryWait = 10 * time.Minute
+	webhookSendTimeout  = 10 * time.Second
+)
+
+// WebhookPayload is the JSON body of a webhook delivery. Incident is the
+// incident after the change; TimelineItem and ActionItem are set for the
+// events about them.
+type WebhookPayload struct {
+	DeliveryID   string        `json:"delivery_id"`
+	Event        WebhookEvent  `json:"event"`
+	Timestamp    time.Time     `json:"timestamp"`
+	Incident     *Incident     `json:"incident"`
+	TimelineItem *TimelineItem `json:"timeline_item,omitempty"`
+	ActionItem   *ActionItem   `json:"action_item,omitempty"`
+}
+
+// DeadLetter records a webhook delivery that failed every attempt.
+type DeadLetter struct {
+	ID         int64        `json:"id"`
+	DeliveryID string       `json:"delivery_id"`
+	URL        string       `json:"url"`
+	Event      WebhookEvent `json:"event"`
+	Payload    string       `json:"payload"`
+	Attempts   int          `json:"attempts"`
+	LastError  string       `json:"last_error"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// SignWebhook computes the X-Hal-Signature header for a delivery: "v1=" and
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+// Receivers should recompute it and compare in constant time, and reject old
+// timestamps.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDispatcher delivers webhook payloads to the configured URLs in the
+// background, retrying failures with exponential backoff and recording
+// deliveries that never succeed as dead letters. Retries wait on timers
+// rather than in the workers, so a dead endpoint doesn't hold up the others.
+type WebhookDispatcher struct {
+	configs *ConfigHolder
+	store   IncidentStore
+	client  *http.Client
+
+	deliveries chan webhookDelivery
+	mu         sync.RWMutex
+	closed     bool
+	// retries holds the deliveries waiting to be retried, so Shutdown can
+	// dead-letter them.
+	retries map[*webhookDelivery]*time.Timer
+	wg      sync.WaitGroup
+}
+
+type webhookDelivery struct {
+	id     string
+	event  WebhookEvent
+	url    string
+	secret string
+	body   []byte
+
+	// attempts counts the attempts made so far, wait is how long to wait
+	// before the next retry and lastErr is why the last attempt failed.
+	attempts int
+	wait     time.Duration
+	lastErr  error
+}
+
+func NewWebhookDispatcher(configs *ConfigHolder, store IncidentStore, client *http.Client) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		configs:    configs,
+		store:      store,
+		client:     client,
+		deliveries: make(chan webhookDelivery, webhookQueueSize),
+		retries:    make(map[*webhookDelivery]*time.Timer),
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Publish sends payload to every webhook subscribed to its event. Events
+// about confidential incidents only go to webhooks that include them, and
+// each decision is audited. The payload is serialised before Publish
+// returns, so callers may go on changing the models. A nil dispatcher
+// publishes nothing.
+func (d *WebhookDispatcher) Publish(ctx context.Context, payload WebhookPayload) {
+	if d == nil {
+		return
+	}
+
+	for _, hook := range d.configs.Get().Webhooks {
+		if !hook.subscribes(payload.Event) {
+			continue
+		}
+		if incident := payload.Incident; incident != nil && incident.Confidential {
+			recordAudit(ctx, d.store, &AuditEntry{IncidentID: incident.ID, Action: AuditWebhook, Allowed: hook.IncludeConfidential, Detail: fmt.Sprintf("%s to %s", payload.Event, hook.URL)})
+			if !hook.IncludeConfidential {
+				continue
+			}
+		}
+
+		payload.DeliveryID = newDeliveryID()
+		payload.Timestamp = time.Now().UTC()
+		body, err := json.Marshal(payload)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to encode webhook payload", "event", payload.Event, "error", err)
+			return
+		}
+
+		delivery := webhookDelivery{id: payload.DeliveryID, event: payload.Event, url: hook.URL, secret: hook.Secret, body: body}
+		if err := d.enqueue(delivery); err != nil {
+			d.deadLetter(delivery, 0, err)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) enqueue(delivery webhookDelivery) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return errors.New("webhook dispatcher is shut down")
+	}
+	select {
+	case d.deliveries <- delivery:
+		return nil
+	default:
+		return errors.New("webhook delivery queue is full")
+	}
+}
+
+func (d *WebhookDispatcher) work() {
+	defer d.wg.Done()
+	for delivery := range d.deliveries {
+		d.deliver(delivery)
+	}
+}
+
+// deliver makes one attempt at a delivery and schedules the next one if it
+// fails and may be retried.
+func (d *WebhookDispatcher) deliver(delivery webhookDelivery) {
+	config := d.configs.Get()
+	delivery.attempts++
+	retry, err := d.send(delivery)
+	if err == nil {
+		slog.Debug("Delivered webhook", "event", delivery.event, "url", delivery.url, "deliveryID", delivery.id, "attempt", delivery.attempts)
+		return
+	}
+	if !retry || delivery.attempts >= config.WebhookMaxAttempts {
+		d.deadLetter(delivery, delivery.attempts, err)
+		return
+	}
+
+	if delivery.wait == 0 {
+		delivery.wait = config.WebhookRetryDelay
+	}
+	delivery.lastErr = err
+	slog.Warn("Webhook delivery failed, retrying", "event", delivery.event, "url", delivery.url, "attempt", delivery.attempts, "retryIn", delivery.wait, "error", err)
+	d.scheduleRetry(delivery)
+}
+
+// scheduleRetry puts the delivery back on the queue once its wait is over.
+func (d *WebhookDispatcher) scheduleRetry(delivery webhookDelivery) {
+	wait := delivery.wait
+	delivery.wait = min(wait*2, webhookMaxRetryWait)
+	pending := &delivery
+
+	d.mu.Lock()
+	closed := d.closed
+	if !closed {
+		d.wg.Add(1)
+		d.retries[pending] = time.AfterFunc(wait, func() { d.retry(pending) })
+	}
+	d.mu.Unlock()
+	if closed {
+		d.deadLetter(delivery, delivery.attempts, fmt.Errorf("%w (shut down before retrying)", delivery.lastErr))
+	}
+}
+
+// retry queues a delivery whose wait is over, unless Shutdown has already
+// dead-lettered it.
+func (d *WebhookDispatcher) retry(pending *webhookDelivery) {
+	defer d.wg.Done()
+	d.mu.Lock()
+	_, ok := d.retries[pending]
+	delete(d.retries, pending)
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := d.enqueue(*pending); err != nil {
+		d.deadLetter(*pending, pending.attempts, fmt.Errorf("%w (%v before retrying)", pending.lastErr, err))
+	}
+}
+
+// send makes one delivery attempt. It reports whether a failure is worth
+// retrying: network errors, 429s and 5xx responses are, other responses are
+// not.
+func (d *WebhookDispatcher) send(delivery webhookDelivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookSendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return false, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HAL-Webhooks")
+	req.Header.Set("X-Hal-Event", string(delivery.event))
+	req.Header.Set("X-Hal-Delivery", delivery.id)
+	req.Header.Set("X-Hal-Timestamp", timestamp)
+	req.Header.Set("X-Hal-Signature", SignWebhook(delivery.secret, timestamp, delivery.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook endpoint returned %s", resp.Status)
+}
+
+func (d *WebhookDispatcher) deadLetter(delivery webhookDelivery, attempts int, cause error) {
+	slog.Error("Webhook delivery failed permanently", "event", delivery.event, "url", delivery.url, "deliveryID", delivery.id, "attempts", attempts, "error", cause)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := d.store.AddDeadLetter(ctx, &DeadLetter{
+		DeliveryID: delivery.id,
+		URL:        delivery.url,
+		Event:      delivery.event,
+		Payload:    string(delivery.body),
+		Attempts:   attempts,
+		LastError:  cause.Error(),
+	})
+	if err != nil {
+		slog.Error("Failed to record webhook dead letter", "deliveryID", delivery.id, "error", err)
+	}
+}
+
+// Shutdown stops accepting deliveries, cancels pending retries, which are
+// dead-lettered, and waits for in-flight attempts until ctx is done.
+func (d *WebhookDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	var cancelled []webhookDelivery
+	if !d.closed {
+		d.closed = true
+		close(d.deliveries)
+		for pending, timer := range d.retries {
+			// A timer that already fired finds its delivery gone and
+			// returns.
+			if timer.Stop() {
+				d.wg.Done()
+			}
+			cancelled = append(cancelled, *pending)
+		}
+		clear(d.retries)
+	}
+	d.mu.Unlock()
+	for _, delivery := range cancelled {
+		d.deadLetter(delivery, delivery.attempts, fmt.Errorf("%w (shut down before retrying)", delivery.lastErr))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("webhook deliveries did not finish: %w", ctx.Err())
+	}
+}
+
+func (w WebhookConfig) subscribes(event WebhookEvent) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
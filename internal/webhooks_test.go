@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhooks(t *testing.T) {
+	app := newTestApp(t)
+
+	var mu sync.Mutex
+	var received []WebhookPayload
+	failed := make(map[string]bool)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Hal-Signature") != SignWebhook("s3cret", r.Header.Get("X-Hal-Timestamp"), body) {
+			t.Errorf("bad signature on %s delivery", r.Header.Get("X-Hal-Event"))
+		}
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		// Fail each delivery once to exercise the retries.
+		if !failed[payload.DeliveryID] {
+			failed[payload.DeliveryID] = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, payload)
+	}))
+	defer receiver.Close()
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer rejecting.Close()
+
+	app.config.Webhooks = []WebhookConfig{
+		{URL: receiver.URL, Secret: "s3cret", Events: []WebhookEvent{WebhookIncidentCreated, WebhookActionItemAdded, WebhookIncidentResolved}},
+		{URL: rejecting.URL, Secret: "other", Events: []WebhookEvent{WebhookIncidentResolved}},
+	}
+
+	app.interaction(t, createIncidentSubmission("V1100", "Checkout is down", "SEV-1"))
+	eventually(t, "incident setup", func() bool { return len(app.slack.Calls("pins.add")) == 2 })
+	app.command(t, app.incident().ChannelID, "resolve")
+
+	eventually(t, "webhook deliveries", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	})
+	mu.Lock()
+	events := make(map[WebhookEvent]WebhookPayload)
+	for _, payload := range received {
+		events[payload.Event] = payload
+	}
+	mu.Unlock()
+	if p := events[WebhookIncidentCreated]; p.Incident == nil || p.Incident.Description != "Checkout is down" {
+		t.Errorf("incident.created payload = %+v", p)
+	}
+	if p := events[WebhookActionItemAdded]; p.ActionItem == nil || p.ActionItem.Description != "Create incident postmortem" {
+		t.Errorf("action_item.added payload = %+v", p)
+	}
+	if p := events[WebhookIncidentResolved]; p.Incident == nil || p.Incident.Status != StatusResolved {
+		t.Errorf("incident.resolved payload = %+v", p)
+	}
+
+	// A 410 is not retried and goes straight to the dead letters.
+	eventually(t, "dead letter", func() bool {
+		letters, _ := app.store.ListDeadLetters(context.Background(), 10)
+		return len(letters) == 1 && letters[0].URL == rejecting.URL && letters[0].Attempts == 1 && letters[0].Event == WebhookIncidentResolved
+	})
+}
+
+func TestWebhooksSkipConfidential(t *testing.T) {
+	app := newTestApp(t)
+
+	var mu sync.Mutex
+	received := make(map[string][]WebhookPayload)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.URL.Path] = append(received[r.URL.Path], payload)
+	}))
+	defer receiver.Close()
+	app.config.Webhooks = []WebhookConfig{
+		{URL: receiver.URL + "/status", Secret: "s3cret", Events: []WebhookEvent{WebhookIncidentCreated}},
+		{URL: receiver.URL + "/security", Secret: "s3cret", Events: []WebhookEvent{WebhookIncidentCreated}, IncludeConfidential: true},
+	}
+
+	var secret Incident
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Suspicious logins", "severity": "SEV-3", "confidential": true}`, &secret); code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+	eventually(t, "delivery", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received["/security"]) == 1
+	})
+	mu.Lock()
+	if len(received["/status"]) != 0 {
+		t.Errorf("confidential incident sent to the status page: %+v", received["/status"])
+	}
+	mu.Unlock()
+
+	entries, _ := app.store.ListAuditEntries(context.Background(), secret.ID)
+	var decisions []string
+	for _, entry := range entries {
+		if entry.Action == AuditWebhook {
+			decisions = append(decisions, fmt.Sprintf("%v %s", entry.Allowed, entry.Detail))
+		}
+	}
+	want := []string{
+		"false incident.created to " + receiver.URL + "/status",
+		"true incident.created to " + receiver.URL + "/security",
+	}
+	if !slices.Equal(decisions, want) {
+		t.Errorf("webhook audit entries = %q, want %q", decisions, want)
+	}
+}
+
+func TestWebhookRetriesDontBlockOtherEndpoints(t *testing.T) {
+	app := newTestApp(t)
+	app.config.WebhookMaxAttempts = 6
+	app.config.WebhookRetryDelay = time.Minute
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	var mu sync.Mutex
+	var received []string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, payload.TimelineItem.Message)
+	}))
+	defer up.Close()
+	app.config.Webhooks = []WebhookConfig{
+		{URL: down.URL, Secret: "s3cret", Events: []WebhookEvent{WebhookTimelineAdded}},
+		{URL: up.URL, Secret: "s3cret", Events: []WebhookEvent{WebhookTimelineAdded}},
+	}
+
+	var incident Incident
+	if code := app.api(t, http.MethodPost, "/incidents", `{"description": "Checkout is down", "severity": "SEV-3"}`, &incident); code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+	// More failing deliveries than there are workers, each waiting a
+	// minute to retry.
+	const notes = 2 * webhookWorkers
+	for i := 0; i < notes; i++ {
+		if code := app.api(t, http.MethodPost, "/incidents/"+incident.ID+"/timeline", fmt.Sprintf(`{"message": "note %d"}`, i), nil); code != http.StatusCreated {
+			t.Fatalf("timeline: status %d", code)
+		}
+	}
+	eventually(t, "deliveries to the healthy endpoint", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		count := 0
+		for _, message := range received {
+			if strings.HasPrefix(message, "note ") {
+				count++
+			}
+		}
+		return count == notes
+	})
+}
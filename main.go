@@ -3,48 +3,68 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/Imagine-Pediatrics/hal/internal"
 	"github.com/gin-gonic/gin"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML configuration file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: hal [-config file]              run the server\n       hal [-config file] incident ...   manage incidents, see `hal incident -h`\n\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	if flag.Arg(0) == "incident" {
+		os.Exit(internal.RunCLI(context.Background(), *configPath, flag.Args(), os.Stdout, os.Stderr))
+	}
+
+	var logLevel slog.LevelVar
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &logLevel}))
 	slog.SetDefault(logger)
 
-	cfg, err := internal.LoadConfig()
+	cfg, err := internal.LoadConfig(*configPath)
 	if err != nil {
 		slog.Error("Failed to load configuration", "error", err)
 		os.Exit(1)
 	}
+	logLevel.Set(cfg.SlogLevel())
+	configs := internal.NewConfigHolder(*configPath, cfg)
 
-	slackClient := slack.New(cfg.SlackToken)
+	store, err := internal.NewSQLStore(cfg.DatabasePath)
+	if err != nil {
+		slog.Error("Failed to open incident store", "error", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	slackClient := slack.New(cfg.SlackToken, slack.OptionAppLevelToken(cfg.SlackAppToken))
 	slackService := internal.NewSlackService(slackClient, cfg)
-	incidentService := internal.NewIncidentService(slackService, cfg)
+	webhooks := internal.NewWebhookDispatcher(configs, store, &http.Client{})
+	incidentService := internal.NewIncidentService(slackService, store, configs, webhooks)
+	queue := internal.NewWorkQueue(cfg.WorkerCount, cfg.WorkQueueSize, cfg.JobTimeout)
 
 	router := gin.Default()
-	router.Use(internal.SlackAuthMiddleware(cfg.SlackSigningSecret))
-	internal.RegisterRoutes(router, incidentService, slackService)
+	internal.RegisterRoutes(router, incidentService, slackService, queue, cfg)
 
 	srv := &http.Server{
-		Addr:    ":" + os.Getenv("PORT"),
+		Addr:    net.JoinHostPort(cfg.ServerHost, strconv.Itoa(cfg.ServerPort)),
 		Handler: router,
 	}
 
-	if srv.Addr == ":" {
-		srv.Addr = ":50051"
-	}
-
 	go func() {
 		slog.Info("Starting server", "address", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -53,11 +73,45 @@ func main() {
 		}
 	}()
 
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	if cfg.SocketMode {
+		socketClient := socketmode.New(slackClient)
+		go func() {
+			if err := internal.RunSocketMode(backgroundCtx, socketClient, incidentService, queue); err != nil {
+				slog.Error("Socket Mode stopped", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	if cfg.LifecycleInterval > 0 {
+		go incidentService.RunLifecycle(backgroundCtx, cfg.LifecycleInterval)
+	}
+
+	// SIGHUP reloads the configuration in place; requests already being
+	// handled finish with the configuration they started with.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	for waiting := true; waiting; {
+		select {
+		case <-reload:
+			next, err := configs.Reload()
+			if err != nil {
+				slog.Error("Failed to reload configuration, keeping the current one", "error", err)
+				continue
+			}
+			logLevel.Set(next.SlogLevel())
+			slog.Info("Reloaded configuration")
+		case <-quit:
+			waiting = false
+		}
+	}
 
 	slog.Info("Shutting down server...")
+	stopBackground()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
@@ -65,5 +119,16 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	slog.Info("Draining background jobs...")
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer drainCancel()
+	if err := queue.Shutdown(drainCtx); err != nil {
+		slog.Error("Background jobs did not finish", "error", err)
+	}
+	// Jobs publish webhooks, so stop deliveries after the queue has drained.
+	if err := webhooks.Shutdown(drainCtx); err != nil {
+		slog.Error("Webhook deliveries did not finish", "error", err)
+	}
+
 	slog.Info("Server exited")
 }